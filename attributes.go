@@ -0,0 +1,36 @@
+package knet
+
+// Value returns the attribute stored on client under key, typed as T.
+// It reports false when the key is missing or holds a value of another type.
+//
+// Example:
+//
+//	server.RegisterHandler(ctx, 0x0200, func(ctx context.Context, client knet.Client, payload []byte) {
+//	    user, ok := knet.Value[*User](client, "user")
+//	    if !ok {
+//	        return
+//	    }
+//	    log.Printf("%s says %s", user.Name, payload)
+//	})
+func Value[T any](client Client, key string) (T, bool) {
+	value, ok := client.Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+// ValueOr returns the attribute stored on client under key, typed as T, or
+// fallback when the key is missing or holds a value of another type.
+//
+// Example:
+//
+//	locale := knet.ValueOr(client, "locale", "en-US")
+func ValueOr[T any](client Client, key string, fallback T) T {
+	if value, ok := Value[T](client, key); ok {
+		return value
+	}
+	return fallback
+}
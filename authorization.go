@@ -0,0 +1,54 @@
+package knet
+
+// PermissionHolder is implemented by principals that carry roles or permissions.
+//
+// Handlers registered with RequirePermissions only run for clients whose
+// principal (see Client.Principal) implements it and holds every required
+// permission. Roles can be modelled as permissions, for example "role:admin".
+type PermissionHolder interface {
+	HasPermission(permission string) bool
+}
+
+// Permissions is a ready-made PermissionHolder backed by a list of names.
+//
+// Example:
+//
+//	cfg.Authenticate = func(r *http.Request) (any, error) {
+//	    return knet.Permissions{"role:support", "tickets:close"}, nil
+//	}
+type Permissions []string
+
+// HasPermission reports whether permission is in the list
+func (p Permissions) HasPermission(permission string) bool {
+	for _, name := range p {
+		if name == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the client's principal holds permission.
+// Clients whose principal is not a PermissionHolder hold no permissions.
+func HasPermission(client Client, permission string) bool {
+	holder, ok := client.Principal().(PermissionHolder)
+	return ok && holder.HasPermission(permission)
+}
+
+// RequirePermissions only lets clients holding every listed permission reach
+// the handler being registered. Other clients get a denial: CmdPermissionDenied
+// for fire-and-forget commands, CmdResponseError with RequestErrPermissionDenied
+// for correlated requests and a JSONRPCPermissionDenied error for JSON-RPC
+// methods. Denials are reported to ServerConfig.OnPermissionDenied.
+//
+// The check runs before any middleware.
+//
+// Example:
+//
+//	server.RegisterHandler(ctx, 0x0200, handleAdminCommand, knet.RequirePermissions("role:admin"))
+//	server.RegisterJSONRPCHandler(ctx, "closeTicket", closeTicket, knet.RequirePermissions("role:support"))
+func RequirePermissions(permissions ...string) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.Permissions = append(o.Permissions, permissions...)
+	}
+}
@@ -0,0 +1,47 @@
+package knet
+
+// BroadcastReport summarizes the outcome of a broadcast.
+//
+// Every targeted client is counted exactly once: the message was either queued
+// for delivery, dropped, or the client was (or became) disconnected.
+type BroadcastReport struct {
+	// Delivered is the number of clients the message was queued for
+	Delivered int
+	// Dropped is the number of clients that did not get the message because
+	// their send queue was full or the broadcast context ended first
+	Dropped int
+	// Closed is the number of clients that were disconnected, including slow
+	// consumers disconnected by the send queue policy
+	Closed int
+	// Errors maps the ID of every client that did not get the message to the
+	// reason. It is only filled when WithClientErrors is used.
+	Errors map[string]error
+}
+
+// Total returns the number of clients the broadcast targeted
+func (r *BroadcastReport) Total() int {
+	return r.Delivered + r.Dropped + r.Closed
+}
+
+// BroadcastOptions holds the settings collected from BroadcastOption values.
+type BroadcastOptions struct {
+	// ClientErrors collects per-client errors in BroadcastReport.Errors
+	ClientErrors bool
+}
+
+// BroadcastOption customizes a single broadcast.
+type BroadcastOption func(*BroadcastOptions)
+
+// WithClientErrors records why each client missed the broadcast in BroadcastReport.Errors.
+//
+// Example:
+//
+//	report, _ := server.BroadcastCommand(ctx, 0x0100, data, knet.WithClientErrors())
+//	for id, err := range report.Errors {
+//	    log.Printf("client %s missed the update: %v", id, err)
+//	}
+func WithClientErrors() BroadcastOption {
+	return func(o *BroadcastOptions) {
+		o.ClientErrors = true
+	}
+}
@@ -1,10 +1,55 @@
 package knet
 
 // Reserved command IDs for internal use.
+//
+// Every command ID from CmdReservedMin up to 0xFFFFFFFF is reserved;
+// handlers cannot be registered for them.
 const (
+	// CmdReservedMin is the lowest reserved command ID
+	CmdReservedMin uint32 = 0xFFFFFF00
+
 	// CmdJSONRPC is reserved for JSON-RPC 2.0 messages
 	CmdJSONRPC      uint32 = 0xFFFFFFFF
 	CmdJSONRPCError uint32 = 0xFFFFFFFE
+
+	// CmdSubscribe is sent by a client to subscribe to a topic pattern (payload: UTF-8 pattern).
+	// The server echoes it back with the same payload once the subscription is active.
+	CmdSubscribe uint32 = 0xFFFFFFFB
+	// CmdUnsubscribe is sent by a client to drop a subscription (payload: UTF-8 pattern).
+	// The server echoes it back with the same payload.
+	CmdUnsubscribe uint32 = 0xFFFFFFFA
+	// CmdSubscribeDenied is sent by the server when a subscription is invalid or
+	// rejected by the authorization hook (payload: UTF-8 pattern).
+	CmdSubscribeDenied uint32 = 0xFFFFFFF9
+
+	// CmdRequest carries a correlated request:
+	// [4 bytes: CommandID][4 bytes: RequestID][N bytes: Payload]
+	CmdRequest uint32 = 0xFFFFFFF8
+	// CmdResponse carries the reply to a correlated request:
+	// [4 bytes: RequestID][N bytes: Payload]
+	CmdResponse uint32 = 0xFFFFFFF7
+	// CmdResponseError carries a failed reply to a correlated request:
+	// [4 bytes: RequestID][4 bytes: Code][N bytes: UTF-8 message]
+	CmdResponseError uint32 = 0xFFFFFFF6
+
+	// CmdGoingAway is sent by the server when it starts draining, before the
+	// connection is closed with CloseGoingAway. Clients should reconnect,
+	// preferably elsewhere (payload: UTF-8 hint, e.g. an alternative URL, may be empty).
+	CmdGoingAway uint32 = 0xFFFFFFF5
+
+	// CmdPermissionDenied is sent by the server when a fire-and-forget command
+	// is rejected because the client lacks a permission:
+	// [4 bytes: CommandID][N bytes: UTF-8 permission]
+	CmdPermissionDenied uint32 = 0xFFFFFFF4
+)
+
+// Error codes carried by CmdResponseError frames.
+// Applications may define their own codes starting at RequestErrApplication.
+const (
+	RequestErrUnknownCommand   uint32 = 1
+	RequestErrInternal         uint32 = 2
+	RequestErrPermissionDenied uint32 = 3
+	RequestErrApplication      uint32 = 1000
 )
 
 // Standard error messages
@@ -15,6 +60,7 @@ const (
 	ErrParseError           = "Parse error"
 	ErrInvalidRequest       = "Invalid Request"
 	ErrMethodNotFound       = "Method not found"
+	ErrInvalidParams        = "Invalid params"
 	ErrInternalError        = "Internal error"
 
 	// Connection errors
@@ -23,6 +69,20 @@ const (
 	ErrContextCancelled     = "client context cancelled"
 	ErrFailedToEncode       = "failed to encode message"
 	ErrServerAlreadyRunning = "server already running"
+	ErrReservedCommand      = "command ID is reserved"
+	ErrDispatchQueueFull    = "dispatch queue full"
+	ErrServerDraining       = "server is draining"
+	ErrSendQueueFull        = "send queue full"
+
+	// Authentication errors
+	ErrAuthenticationRequired = "authentication required"
+	ErrAuthenticationFailed   = "authentication failed"
+	ErrAuthenticationTimeout  = "authentication timeout"
+	ErrPermissionDenied       = "Permission denied"
+
+	// Room and topic errors
+	ErrInvalidRoom  = "room name must not be empty"
+	ErrInvalidTopic = "invalid topic"
 )
 
 // JSON-RPC error codes (following JSON-RPC 2.0 specification)
@@ -32,6 +92,10 @@ const (
 	JSONRPCMethodNotFound = -32601
 	JSONRPCInvalidParams  = -32602
 	JSONRPCInternalError  = -32603
+
+	// JSONRPCPermissionDenied is sent, in the implementation-defined server
+	// error range, when the client lacks a required permission
+	JSONRPCPermissionDenied = -32001
 )
 
 // JSON-RPC version
@@ -23,14 +23,14 @@
 //	server := ws.New(":8080", rateLimitConfig, ws.AllOrigins())
 //
 //	// Register command handlers (command pattern)
-//	server.RegisterHandler(ctx, 0x01, func(client knet.Client, payload []byte) {
+//	server.RegisterHandler(ctx, 0x01, func(ctx context.Context, client knet.Client, payload []byte) {
 //	    // Process the message and optionally send a response
 //	    response := []byte("pong")
 //	    client.Send(ctx, 0x01, response)
 //	})
 //
 //	// Optional: Register JSON-RPC handlers
-//	server.RegisterJSONRPCHandler(ctx, "getStatus", func(params map[string]interface{}) (interface{}, error) {
+//	server.RegisterJSONRPCHandler(ctx, "getStatus", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 //	    return map[string]string{"status": "ok"}, nil
 //	})
 //
@@ -82,6 +82,7 @@
 //   - Write timeout: 10s (prevents slow clients)
 //   - Automatic keepalive with pong handler
 //   - Origin validation via CheckOriginFn
+//   - TLS and mutual TLS (verified client certificate via Client.PeerCertificate)
 //
 // # Performance
 //
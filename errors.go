@@ -0,0 +1,156 @@
+package knet
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RequestError is the typed error of a correlated request.
+//
+// Request handlers return it to choose the code sent in the CmdResponseError
+// frame; any other error is reported as RequestErrInternal with its message.
+// Callers of correlated requests receive a *RequestError when the peer answers
+// with an error frame, so it can be inspected with errors.As.
+//
+// Example:
+//
+//	return nil, &knet.RequestError{Code: knet.RequestErrApplication + 1, Message: "insufficient funds"}
+type RequestError struct {
+	Code    uint32
+	Message string
+}
+
+// Error implements the error interface
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("request error %d: %s", e.Code, e.Message)
+}
+
+// RPCError is the typed error of a JSON-RPC method.
+//
+// JSON-RPC handlers and middleware return it, possibly wrapped, to choose the
+// code, message and data of the error response. Codes from -32768 to -32000
+// are reserved by the specification; use other values for application errors.
+// Any other error is answered with JSONRPCInternalError without exposing its
+// text, unless the server's JSON-RPC error mapper translates it.
+//
+// Example:
+//
+//	return nil, &knet.RPCError{Code: 1001, Message: "insufficient funds", Data: map[string]int{"balance": 3}}
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// HandlerError describes a handler that panicked or failed with nowhere to
+// send the error. It is passed to the server's OnHandlerError callback.
+type HandlerError struct {
+	// ClientID is the ID of the client whose message was being handled
+	ClientID string
+	// CommandID is the command being handled; CmdJSONRPC for JSON-RPC methods
+	CommandID uint32
+	// Method is the JSON-RPC method name, empty for binary commands
+	Method string
+	// Err is the error returned by the handler, or one describing the panic
+	Err error
+	// Panic is the recovered value, nil when the handler returned an error
+	Panic any
+	// Stack is the stack trace of the panicking goroutine, nil when not a panic
+	Stack []byte
+}
+
+// Error implements the error interface
+func (e *HandlerError) Error() string {
+	if e.Method != "" {
+		return fmt.Sprintf("handler for method %q failed for client %s: %v", e.Method, e.ClientID, e.Err)
+	}
+	return fmt.Sprintf("handler for command 0x%08X failed for client %s: %v", e.CommandID, e.ClientID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// ParamsError reports JSON-RPC params that could not be decoded into the
+// parameters a method expects. It is answered with JSONRPCInvalidParams and,
+// as data, the offending JSON field or offset; the decoding error itself is
+// not sent, as it names Go types.
+type ParamsError struct {
+	// Method is the JSON-RPC method name
+	Method string
+	// Err is the decoding error
+	Err error
+}
+
+// Error implements the error interface
+func (e *ParamsError) Error() string {
+	return fmt.Sprintf("%s for method %q: %v", ErrInvalidParams, e.Method, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParamsError) Unwrap() error {
+	return e.Err
+}
+
+// PermissionError describes a message rejected because the client's principal
+// lacks a required permission. It is passed to the server's OnPermissionDenied
+// callback. Middleware and request handlers may also return it to deny a
+// message with the standard denial response.
+type PermissionError struct {
+	// ClientID is the ID of the client whose message was denied
+	ClientID string
+	// CommandID is the command being denied; CmdJSONRPC for JSON-RPC methods
+	CommandID uint32
+	// Method is the JSON-RPC method name, empty for binary commands
+	Method string
+	// Permission is the permission the client lacks
+	Permission string
+}
+
+// Error implements the error interface
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPermissionDenied, e.Permission)
+}
+
+// AuthError rejects a connection during authentication.
+//
+// Return it from ServerConfig.Authenticate to choose the HTTP status and body
+// sent instead of upgrading the connection; any other error is answered with
+// 401 Unauthorized.
+//
+// Example:
+//
+//	return nil, &knet.AuthError{Status: http.StatusForbidden, Message: "account suspended"}
+type AuthError struct {
+	// Status is the HTTP status code, 401 Unauthorized when zero
+	Status int
+	// Message is the response body, the status text when empty
+	Message string
+}
+
+// Error implements the error interface
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed (%d): %s", e.StatusCode(), e.Body())
+}
+
+// StatusCode returns the HTTP status to reject the request with
+func (e *AuthError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusUnauthorized
+	}
+	return e.Status
+}
+
+// Body returns the response body to reject the request with
+func (e *AuthError) Body() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode())
+	}
+	return e.Message
+}
@@ -11,17 +11,31 @@
  *   receive messages from the server.
  * 
  * - JSON-RPC: Synchronous request-response pattern. The sendJSONRPC() method
- *   returns a Promise that resolves when the server sends a response.
+ *   returns a Promise that resolves when the server sends a response. Methods
+ *   registered with onJSONRPC() answer the server's CallJSONRPC and receive its
+ *   NotifyJSONRPC notifications.
+ *
+ * - Correlated requests: request() sends a binary command with a request ID and
+ *   returns a Promise that resolves with the reply payload (or rejects with a
+ *   RequestError carrying the server's error code).
  *
  * This is a direct plain-JS conversion of the TypeScript implementation.
  */
 
 /* Reserved command IDs (as defined in the server) */
 const ReservedCommands = {
+  RESERVED_MIN: 0xFFFFFF00,
   JSON_RPC: 0xFFFFFFFF,
   JSON_RPC_ERROR: 0xFFFFFFFE,
   INVALID_COMMAND: 0xFFFFFFFD,
   COMMAND_ERROR: 0xFFFFFFFC,
+  SUBSCRIBE: 0xFFFFFFFB,
+  UNSUBSCRIBE: 0xFFFFFFFA,
+  SUBSCRIBE_DENIED: 0xFFFFFFF9,
+  REQUEST: 0xFFFFFFF8,
+  RESPONSE: 0xFFFFFFF7,
+  RESPONSE_ERROR: 0xFFFFFFF6,
+  GOING_AWAY: 0xFFFFFFF5,
 };
 
 /* Connection state */
@@ -43,10 +57,17 @@ class KephasClient {
       maxReconnectAttempts: config.maxReconnectAttempts !== undefined ? config.maxReconnectAttempts : Infinity,
       connectionTimeout: config.connectionTimeout !== undefined ? config.connectionTimeout : 10000,
       debug: config.debug !== undefined ? config.debug : false,
+      onGoingAway: config.onGoingAway || null,
     };
     this.state = ConnectionState.DISCONNECTED;
     this.handlers = new Map();
     this.jsonRpcHandlers = new Map();
+    this.pendingJsonRpc = new Map();
+    this.nextJsonRpcId = 1;
+    this.pendingSubscriptions = new Map();
+    this.pendingRequests = new Map();
+    this.requestHandlers = new Map();
+    this.nextRequestId = 1;
     this.reconnectAttempts = 0;
     this.reconnectTimer = null;
     this.connectionPromise = null;
@@ -126,7 +147,7 @@ class KephasClient {
   }
 
   on(commandId, handler) {
-    if (commandId >= ReservedCommands.COMMAND_ERROR) {
+    if (commandId >= ReservedCommands.RESERVED_MIN) {
       throw new Error(`Command ID ${commandId.toString(16)} is reserved`);
     }
     this.handlers.set(commandId, handler);
@@ -161,44 +182,248 @@ class KephasClient {
     return this.sendString(commandId, json);
   }
 
-  async sendJSONRPC(method, params, id) {
+  sendJSONRPC(method, params, id) {
     const request = {
       jsonrpc: '2.0',
       method: method,
       params: params,
-      id: id !== undefined ? id : Date.now(),
+      id: id !== undefined ? id : this.nextJsonRpcId++,
     };
 
     return new Promise((resolve, reject) => {
-      const responseHandler = (payload) => {
-        try {
-          const decoder = new TextDecoder();
-          const json = decoder.decode(payload);
-          const response = JSON.parse(json);
-
-          if (response.id === request.id) {
-            this.off(ReservedCommands.JSON_RPC);
-            if (response.error) {
-              reject(new Error(response.error.message));
-            } else {
-              resolve(response);
-            }
-          }
-        } catch (error) {
-          reject(error);
-        }
+      const timer = setTimeout(() => {
+        this.pendingJsonRpc.delete(request.id);
+        reject(new Error('JSON-RPC request timeout'));
+      }, 30000);
+
+      this.pendingJsonRpc.set(request.id, { resolve, reject, timer });
+      this.sendJSON(ReservedCommands.JSON_RPC, request).catch((error) => {
+        clearTimeout(timer);
+        this.pendingJsonRpc.delete(request.id);
+        reject(error);
+      });
+    });
+  }
+
+  /**
+   * Handle a JSON-RPC method called by the server with CallJSONRPC or
+   * NotifyJSONRPC. The handler receives the params and returns (or resolves to)
+   * the result; throwing sends an error object (set error.code and error.data to
+   * choose them). Notifications are never answered.
+   */
+  onJSONRPC(method, handler) {
+    this.jsonRpcHandlers.set(method, handler);
+  }
+
+  offJSONRPC(method) {
+    this.jsonRpcHandlers.delete(method);
+  }
+
+  handleJSONRPC(payload) {
+    const message = JSON.parse(new TextDecoder().decode(payload));
+
+    if (Array.isArray(message)) {
+      message.forEach((response) => this.handleJSONRPCResponse(response));
+      return;
+    }
+    if (typeof message.method === 'string') {
+      this.handleServerJSONRPC(message);
+      return;
+    }
+    this.handleJSONRPCResponse(message);
+  }
+
+  handleJSONRPCResponse(response) {
+    const pending = this.pendingJsonRpc.get(response.id);
+    if (!pending) {
+      return;
+    }
+
+    this.pendingJsonRpc.delete(response.id);
+    clearTimeout(pending.timer);
+    if (response.error) {
+      const error = new Error(response.error.message);
+      error.code = response.error.code;
+      error.data = response.error.data;
+      pending.reject(error);
+    } else {
+      pending.resolve(response);
+    }
+  }
+
+  async handleServerJSONRPC(request) {
+    const isNotification = request.id === undefined;
+    const handler = this.jsonRpcHandlers.get(request.method);
+    const response = { jsonrpc: '2.0', id: request.id };
+
+    try {
+      if (!handler) {
+        const error = new Error('Method not found');
+        error.code = -32601;
+        throw error;
+      }
+      const result = await handler(request.params);
+      response.result = result === undefined ? null : result;
+    } catch (error) {
+      response.error = {
+        code: error && error.code !== undefined ? error.code : -32603,
+        message: error && error.message ? error.message : String(error),
       };
+      if (error && error.data !== undefined) {
+        response.error.data = error.data;
+      }
+    }
 
-      this.on(ReservedCommands.JSON_RPC, responseHandler);
-      this.sendJSON(ReservedCommands.JSON_RPC, request).catch(reject);
+    if (!isNotification) {
+      await this.sendJSON(ReservedCommands.JSON_RPC, response).catch(() => {});
+    }
+  }
 
-      setTimeout(() => {
-        this.off(ReservedCommands.JSON_RPC);
-        reject(new Error('JSON-RPC request timeout'));
-      }, 30000);
+  /**
+   * Send a correlated request to a handler registered with RegisterRequestHandler.
+   * Resolves with the reply payload (Uint8Array); rejects with a RequestError
+   * (error.code) when the server answers with an error frame.
+   */
+  request(commandId, payload, timeoutMs) {
+    payload = payload || new Uint8Array(0);
+    const requestId = this.nextRequestId;
+    this.nextRequestId = (this.nextRequestId + 1) >>> 0 || 1;
+
+    const body = new Uint8Array(8 + payload.length);
+    const view = new DataView(body.buffer);
+    view.setUint32(0, commandId, false);
+    view.setUint32(4, requestId, false);
+    body.set(payload, 8);
+
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => {
+        this.pendingRequests.delete(requestId);
+        reject(new Error('Request timeout'));
+      }, timeoutMs !== undefined ? timeoutMs : 30000);
+
+      this.pendingRequests.set(requestId, { resolve, reject, timer });
+      this.send(ReservedCommands.REQUEST, body).catch((error) => {
+        clearTimeout(timer);
+        this.pendingRequests.delete(requestId);
+        reject(error);
+      });
     });
   }
 
+  /**
+   * Answer calls made by the server with client.Call(). The handler receives the
+   * request payload and returns (or resolves to) the reply payload; throwing sends
+   * an error frame (set error.code to choose the code).
+   */
+  onRequest(commandId, handler) {
+    this.requestHandlers.set(commandId, handler);
+  }
+
+  offRequest(commandId) {
+    this.requestHandlers.delete(commandId);
+  }
+
+  async handleServerRequest(payload) {
+    if (payload.length < 8) {
+      return;
+    }
+
+    const view = new DataView(payload.buffer, payload.byteOffset, payload.byteLength);
+    const commandId = view.getUint32(0, false);
+    const requestId = view.getUint32(4, false);
+    const handler = this.requestHandlers.get(commandId);
+
+    try {
+      if (!handler) {
+        const error = new Error(`unknown command: 0x${commandId.toString(16).padStart(8, '0')}`);
+        error.code = 1;
+        throw error;
+      }
+
+      const result = (await handler(payload.subarray(8))) || new Uint8Array(0);
+      const body = new Uint8Array(4 + result.length);
+      new DataView(body.buffer).setUint32(0, requestId, false);
+      body.set(result, 4);
+      await this.send(ReservedCommands.RESPONSE, body);
+    } catch (error) {
+      const message = new TextEncoder().encode(error && error.message ? error.message : String(error));
+      const body = new Uint8Array(8 + message.length);
+      const bodyView = new DataView(body.buffer);
+      bodyView.setUint32(0, requestId, false);
+      bodyView.setUint32(4, error && error.code !== undefined ? error.code : 2, false);
+      body.set(message, 8);
+      await this.send(ReservedCommands.RESPONSE_ERROR, body).catch(() => {});
+    }
+  }
+
+  handleResponse(commandId, payload) {
+    if (payload.length < 4) {
+      return;
+    }
+
+    const view = new DataView(payload.buffer, payload.byteOffset, payload.byteLength);
+    const requestId = view.getUint32(0, false);
+    const pending = this.pendingRequests.get(requestId);
+    if (!pending) {
+      return;
+    }
+
+    this.pendingRequests.delete(requestId);
+    clearTimeout(pending.timer);
+
+    if (commandId === ReservedCommands.RESPONSE) {
+      pending.resolve(payload.subarray(4));
+      return;
+    }
+
+    const code = payload.length >= 8 ? view.getUint32(4, false) : 0;
+    const error = new Error(new TextDecoder().decode(payload.subarray(8)));
+    error.name = 'RequestError';
+    error.code = code;
+    pending.reject(error);
+  }
+
+  /**
+   * Subscribe to a topic pattern ("orders.eu.*", "metrics.>").
+   * Resolves once the server acknowledges, rejects if it denies the subscription.
+   * Publications arrive on the command IDs chosen by the server; register them with on().
+   */
+  subscribe(pattern) {
+    return this.sendTopicRequest(ReservedCommands.SUBSCRIBE, pattern);
+  }
+
+  unsubscribe(pattern) {
+    return this.sendTopicRequest(ReservedCommands.UNSUBSCRIBE, pattern);
+  }
+
+  sendTopicRequest(commandId, pattern) {
+    return new Promise((resolve, reject) => {
+      const key = `${commandId}:${pattern}`;
+      this.pendingSubscriptions.set(key, { resolve, reject });
+      this.sendString(commandId, pattern).catch((error) => {
+        this.pendingSubscriptions.delete(key);
+        reject(error);
+      });
+    });
+  }
+
+  handleTopicReply(commandId, payload) {
+    const pattern = new TextDecoder().decode(payload);
+    const requestId = commandId === ReservedCommands.SUBSCRIBE_DENIED ? ReservedCommands.SUBSCRIBE : commandId;
+    const key = `${requestId}:${pattern}`;
+    const pending = this.pendingSubscriptions.get(key);
+    if (!pending) {
+      return;
+    }
+
+    this.pendingSubscriptions.delete(key);
+    if (commandId === ReservedCommands.SUBSCRIBE_DENIED) {
+      pending.reject(new Error(`Subscription denied: ${pattern}`));
+    } else {
+      pending.resolve(pattern);
+    }
+  }
+
   encode(commandId, payload) {
     const buffer = new ArrayBuffer(4 + payload.length);
     const view = new DataView(buffer);
@@ -243,6 +468,38 @@ class KephasClient {
       const { commandId, payload } = this.decode(event.data);
       this.log(`Received command 0x${commandId.toString(16).padStart(8, '0')} with ${payload.length} bytes`);
 
+      if (commandId === ReservedCommands.SUBSCRIBE ||
+          commandId === ReservedCommands.UNSUBSCRIBE ||
+          commandId === ReservedCommands.SUBSCRIBE_DENIED) {
+        this.handleTopicReply(commandId, payload);
+        return;
+      }
+
+      if (commandId === ReservedCommands.RESPONSE || commandId === ReservedCommands.RESPONSE_ERROR) {
+        this.handleResponse(commandId, payload);
+        return;
+      }
+
+      if (commandId === ReservedCommands.REQUEST) {
+        this.handleServerRequest(payload);
+        return;
+      }
+
+      if (commandId === ReservedCommands.JSON_RPC) {
+        this.handleJSONRPC(payload);
+        return;
+      }
+
+      if (commandId === ReservedCommands.GOING_AWAY) {
+        // The server is draining; it closes the connection once in-flight work is done
+        const hint = new TextDecoder().decode(payload);
+        this.log(`Server is going away${hint ? ` (hint: ${hint})` : ''}`);
+        if (this.config.onGoingAway) {
+          this.config.onGoingAway(hint);
+        }
+        return;
+      }
+
       const handler = this.handlers.get(commandId);
       if (handler) {
         Promise.resolve(handler(payload)).catch((error) => {
@@ -365,8 +622,24 @@ await client.connect();
 // Send message (fire-and-forget, no response expected)
 await client.sendString(0x0001, 'Hello, server!');
 
+// Correlated request to a handler registered with RegisterRequestHandler
+const reply = await client.request(0x0200, new TextEncoder().encode('account-1'));
+
+// Answer calls made by the server with client.Call()
+client.onRequest(0x0300, async (payload) => {
+  const ok = window.confirm(new TextDecoder().decode(payload));
+  return new TextEncoder().encode(ok ? 'yes' : 'no');
+});
+
+// Subscribe to topics published by the server
+await client.subscribe('orders.eu.*');
+
 // For request-response pattern, use JSON-RPC
 const response = await client.sendJSONRPC('getUserInfo', { userId: 123 });
 console.log('User info:', response.result);
 
+// Answer JSON-RPC calls and notifications sent by the server
+client.onJSONRPC('ui.confirm', async (params) => window.confirm(params.text));
+client.onJSONRPC('chat.message', (params) => console.log(`${params.from}: ${params.text}`));
+
 */
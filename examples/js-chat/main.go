@@ -98,7 +98,7 @@ func (cs *ChatServer) Start(ctx context.Context) error {
 	return cs.server.Start(ctx)
 }
 
-func (cs *ChatServer) handleChatMessage(client knet.Client, payload []byte) {
+func (cs *ChatServer) handleChatMessage(ctx context.Context, client knet.Client, payload []byte) {
 	var msg ChatMessage
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		log.Printf("Invalid message format: %v", err)
@@ -116,12 +116,12 @@ func (cs *ChatServer) handleChatMessage(client knet.Client, payload []byte) {
 	}
 
 	// Broadcast to all clients
-	if err := cs.server.BroadcastCommand(cs.ctx, ChatMessageCommand, responseData); err != nil {
+	if _, err := cs.server.BroadcastCommand(cs.ctx, ChatMessageCommand, responseData); err != nil {
 		log.Printf("Failed to broadcast message: %v", err)
 	}
 }
 
-func (cs *ChatServer) handleGetUsers(client knet.Client, payload []byte) {
+func (cs *ChatServer) handleGetUsers(ctx context.Context, client knet.Client, payload []byte) {
 	cs.clientsMux.RLock()
 	defer cs.clientsMux.RUnlock()
 
@@ -137,12 +137,12 @@ func (cs *ChatServer) handleGetUsers(client knet.Client, payload []byte) {
 	}
 
 	// Send users list back to the requesting client
-	if err := client.Send(cs.ctx, UsersListCommand, data); err != nil {
+	if err := client.Send(ctx, UsersListCommand, data); err != nil {
 		log.Printf("Failed to send users list: %v", err)
 	}
 }
 
-func (cs *ChatServer) handleUserInfo(client knet.Client, payload []byte) {
+func (cs *ChatServer) handleUserInfo(ctx context.Context, client knet.Client, payload []byte) {
 	var userInfo struct {
 		Username string `json:"username"`
 	}
@@ -0,0 +1,141 @@
+package knet
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// MessageKind tells which dispatch path a Message is travelling through.
+type MessageKind int
+
+const (
+	// KindCommand is a fire-and-forget binary command (RegisterHandler).
+	KindCommand MessageKind = iota
+	// KindRequest is a correlated binary request (RegisterRequestHandler).
+	KindRequest
+	// KindJSONRPC is a JSON-RPC method call (RegisterJSONRPCHandler).
+	KindJSONRPC
+)
+
+// Message describes an inbound message being dispatched to a handler.
+type Message struct {
+	// Kind is the dispatch path of the message.
+	Kind MessageKind
+	// CommandID is the command the message targets. JSON-RPC calls use CmdJSONRPC.
+	CommandID uint32
+	// Method is the JSON-RPC method name, empty for binary messages.
+	Method string
+	// Payload is the binary payload, or the raw JSON-RPC params.
+	// It references the read buffer - do not modify it.
+	Payload []byte
+	// Sequence numbers the messages read from a client, starting at 1.
+	Sequence uint64
+	// ReceivedAt is when the message was read from the connection.
+	ReceivedAt time.Time
+}
+
+// messageContextKey is the context key of the message being handled
+type messageContextKey struct{}
+
+// NewMessageContext returns a copy of ctx carrying msg.
+// The server does this for every handler; it is exported for tests that call
+// handlers directly.
+func NewMessageContext(ctx context.Context, msg *Message) context.Context {
+	return context.WithValue(ctx, messageContextKey{}, msg)
+}
+
+// MessageFromContext returns the message a handler context was created for,
+// giving handlers access to its command ID, sequence number and receive time.
+//
+// Example:
+//
+//	server.RegisterHandler(ctx, 0x0100, func(ctx context.Context, client knet.Client, payload []byte) {
+//	    if msg, ok := knet.MessageFromContext(ctx); ok {
+//	        log.Printf("seq=%d queued for %s", msg.Sequence, time.Since(msg.ReceivedAt))
+//	    }
+//	})
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	msg, ok := ctx.Value(messageContextKey{}).(*Message)
+	return msg, ok
+}
+
+// Handler is the common shape of every registered handler, so a single
+// middleware can wrap binary commands, correlated requests and JSON-RPC methods.
+//
+// The returned value is the reply: ignored for fire-and-forget commands, the
+// []byte payload for correlated requests and the result for JSON-RPC methods.
+// A returned error is sent as CmdResponseError for requests and as a JSON-RPC
+// error for methods.
+//
+// ctx is derived from the client context: it is cancelled when the client
+// disconnects or the server stops, and when the handler's timeout (see
+// WithTimeout) expires. It carries msg (see MessageFromContext).
+type Handler func(ctx context.Context, client Client, msg *Message) (any, error)
+
+// Middleware wraps a Handler with cross-cutting behavior such as authentication,
+// logging or timing. Middleware may short-circuit by returning without calling next.
+//
+// Example:
+//
+//	func logging(next knet.Handler) knet.Handler {
+//	    return func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+//	        start := time.Now()
+//	        result, err := next(ctx, client, msg)
+//	        log.Printf("cmd=0x%X method=%q took=%s err=%v", msg.CommandID, msg.Method, time.Since(start), err)
+//	        return result, err
+//	    }
+//	}
+type Middleware func(next Handler) Handler
+
+// HandlerOptions holds the per-handler settings collected from HandlerOption values.
+type HandlerOptions struct {
+	// Middleware wraps only this handler, inside the server-wide middleware.
+	Middleware []Middleware
+	// Timeout bounds the handler context, zero means no timeout.
+	Timeout time.Duration
+	// Permissions must all be held by the client's principal (see RequirePermissions).
+	Permissions []string
+	// Name and Description document the handler (see WithDoc).
+	Name        string
+	Description string
+	// RequestType and ResponseType describe the payloads (see WithTypes).
+	RequestType  reflect.Type
+	ResponseType reflect.Type
+}
+
+// HandlerOption customizes a single handler registration.
+type HandlerOption func(*HandlerOptions)
+
+// WithMiddleware adds middleware that only wraps the handler being registered.
+// The first middleware is the outermost.
+//
+// Example:
+//
+//	server.RegisterHandler(ctx, 0x0200, handleAdminCommand, knet.WithMiddleware(requireAdmin))
+func WithMiddleware(mw ...Middleware) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.Middleware = append(o.Middleware, mw...)
+	}
+}
+
+// WithTimeout cancels the handler context after d. The timeout covers the
+// middleware and handler, not the time spent waiting in a dispatch queue.
+// Handlers must watch ctx.Done() for it to take effect.
+//
+// Example:
+//
+//	server.RegisterRequestHandler(ctx, 0x0200, lookupBalance, knet.WithTimeout(2*time.Second))
+func WithTimeout(d time.Duration) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.Timeout = d
+	}
+}
+
+// Chain wraps h with mw so that mw[0] is the outermost middleware.
+func Chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
@@ -0,0 +1,120 @@
+package knet
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+// Handshake is an immutable snapshot of the HTTP request that was upgraded to
+// a WebSocket connection.
+//
+// Accessors return copies, so callers may modify what they get back without
+// affecting other handlers. The TLS state is shared and must be treated as
+// read-only.
+type Handshake struct {
+	header      http.Header
+	url         url.URL
+	host        string
+	cookies     []*http.Cookie
+	tls         *tls.ConnectionState
+	subprotocol string
+}
+
+// NewHandshake snapshots r together with the negotiated subprotocol.
+// The server does this for every connection; it is exported for tests.
+func NewHandshake(r *http.Request, subprotocol string) *Handshake {
+	h := &Handshake{
+		header:      r.Header.Clone(),
+		host:        r.Host,
+		cookies:     r.Cookies(),
+		tls:         r.TLS,
+		subprotocol: subprotocol,
+	}
+	if r.URL != nil {
+		h.url = *r.URL
+		if r.URL.User != nil {
+			user := *r.URL.User
+			h.url.User = &user
+		}
+	}
+	if h.header == nil {
+		h.header = http.Header{}
+	}
+	return h
+}
+
+// Header returns a copy of the request headers.
+func (h *Handshake) Header() http.Header {
+	return h.header.Clone()
+}
+
+// HeaderValue returns the first value of the named request header, or "".
+//
+// Example:
+//
+//	requestID := client.Handshake().HeaderValue("X-Request-ID")
+func (h *Handshake) HeaderValue(name string) string {
+	return h.header.Get(name)
+}
+
+// URL returns a copy of the requested URL.
+func (h *Handshake) URL() *url.URL {
+	u := h.url
+	if h.url.User != nil {
+		user := *h.url.User
+		u.User = &user
+	}
+	return &u
+}
+
+// Path returns the requested path, such as "/ws".
+func (h *Handshake) Path() string {
+	return h.url.Path
+}
+
+// Query returns the parsed query parameters of the requested URL.
+//
+// Example:
+//
+//	token := client.Handshake().Query().Get("token")
+func (h *Handshake) Query() url.Values {
+	return h.url.Query()
+}
+
+// Host returns the host the request was sent to.
+func (h *Handshake) Host() string {
+	return h.host
+}
+
+// Cookies returns copies of the cookies sent with the request.
+func (h *Handshake) Cookies() []*http.Cookie {
+	cookies := make([]*http.Cookie, len(h.cookies))
+	for i, cookie := range h.cookies {
+		c := *cookie
+		cookies[i] = &c
+	}
+	return cookies
+}
+
+// Cookie returns a copy of the named cookie, or http.ErrNoCookie.
+func (h *Handshake) Cookie(name string) (*http.Cookie, error) {
+	for _, cookie := range h.cookies {
+		if cookie.Name == name {
+			c := *cookie
+			return &c, nil
+		}
+	}
+	return nil, http.ErrNoCookie
+}
+
+// TLS returns the TLS state of the connection, nil for plain connections.
+// It must not be modified.
+func (h *Handshake) TLS() *tls.ConnectionState {
+	return h.tls
+}
+
+// Subprotocol returns the negotiated WebSocket subprotocol, or "" when none was negotiated.
+func (h *Handshake) Subprotocol() string {
+	return h.subprotocol
+}
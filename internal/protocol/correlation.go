@@ -0,0 +1,77 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Correlated messages travel as the payload of reserved command IDs
+// (knet.CmdRequest, knet.CmdResponse and knet.CmdResponseError):
+//
+//	request:  [4 bytes: CommandID][4 bytes: RequestID][N bytes: Payload]
+//	response: [4 bytes: RequestID][N bytes: Payload]
+//	error:    [4 bytes: RequestID][4 bytes: Code][N bytes: UTF-8 message]
+//
+// All integers are big-endian.
+
+const (
+	requestHeaderSize  = 8
+	responseHeaderSize = 4
+	errorHeaderSize    = 8
+)
+
+// EncodeRequest builds the body of a correlated request for commandID.
+func EncodeRequest(commandID, requestID uint32, payload []byte) []byte {
+	out := make([]byte, requestHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(out[0:4], commandID)
+	binary.BigEndian.PutUint32(out[4:8], requestID)
+	copy(out[requestHeaderSize:], payload)
+	return out
+}
+
+// DecodeRequest splits a correlated request body into its command ID, request ID and payload.
+// The payload slice references the input data - do not modify it.
+func DecodeRequest(data []byte) (commandID, requestID uint32, payload []byte, err error) {
+	if len(data) < requestHeaderSize {
+		return 0, 0, nil, errors.New("request too short")
+	}
+	commandID = binary.BigEndian.Uint32(data[0:4])
+	requestID = binary.BigEndian.Uint32(data[4:8])
+	return commandID, requestID, data[requestHeaderSize:], nil
+}
+
+// EncodeResponse builds the body of a successful reply to requestID.
+func EncodeResponse(requestID uint32, payload []byte) []byte {
+	out := make([]byte, responseHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(out[0:4], requestID)
+	copy(out[responseHeaderSize:], payload)
+	return out
+}
+
+// DecodeResponse splits a reply body into its request ID and payload.
+// The payload slice references the input data - do not modify it.
+func DecodeResponse(data []byte) (requestID uint32, payload []byte, err error) {
+	if len(data) < responseHeaderSize {
+		return 0, nil, errors.New("response too short")
+	}
+	return binary.BigEndian.Uint32(data[0:4]), data[responseHeaderSize:], nil
+}
+
+// EncodeError builds the body of an error reply to requestID.
+func EncodeError(requestID, code uint32, message string) []byte {
+	out := make([]byte, errorHeaderSize+len(message))
+	binary.BigEndian.PutUint32(out[0:4], requestID)
+	binary.BigEndian.PutUint32(out[4:8], code)
+	copy(out[errorHeaderSize:], message)
+	return out
+}
+
+// DecodeError splits an error reply body into its request ID, code and message.
+func DecodeError(data []byte) (requestID, code uint32, message string, err error) {
+	if len(data) < errorHeaderSize {
+		return 0, 0, "", errors.New("error response too short")
+	}
+	requestID = binary.BigEndian.Uint32(data[0:4])
+	code = binary.BigEndian.Uint32(data[4:8])
+	return requestID, code, string(data[errorHeaderSize:]), nil
+}
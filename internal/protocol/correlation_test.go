@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestRequestRoundTrip tests encoding and decoding of correlated requests
+func TestRequestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	body := EncodeRequest(0x0100, 42, []byte("ping"))
+
+	commandID, requestID, payload, err := DecodeRequest(body)
+	if err != nil {
+		t.Fatalf("DecodeRequest() error = %v", err)
+	}
+	if commandID != 0x0100 || requestID != 42 || !bytes.Equal(payload, []byte("ping")) {
+		t.Errorf("DecodeRequest() = (0x%x, %d, %q), want (0x100, 42, %q)", commandID, requestID, payload, "ping")
+	}
+
+	if _, _, _, err := DecodeRequest([]byte{0, 0, 0, 1}); err == nil {
+		t.Error("expected DecodeRequest() to reject a short body")
+	}
+}
+
+// TestResponseRoundTrip tests encoding and decoding of replies
+func TestResponseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	requestID, payload, err := DecodeResponse(EncodeResponse(7, nil))
+	if err != nil {
+		t.Fatalf("DecodeResponse() error = %v", err)
+	}
+	if requestID != 7 || len(payload) != 0 {
+		t.Errorf("DecodeResponse() = (%d, %q), want (7, empty)", requestID, payload)
+	}
+
+	if _, _, err := DecodeResponse([]byte{1}); err == nil {
+		t.Error("expected DecodeResponse() to reject a short body")
+	}
+}
+
+// TestErrorRoundTrip tests encoding and decoding of error replies
+func TestErrorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	requestID, code, message, err := DecodeError(EncodeError(9, 3, "boom"))
+	if err != nil {
+		t.Fatalf("DecodeError() error = %v", err)
+	}
+	if requestID != 9 || code != 3 || message != "boom" {
+		t.Errorf("DecodeError() = (%d, %d, %q), want (9, 3, %q)", requestID, code, message, "boom")
+	}
+
+	if _, _, _, err := DecodeError([]byte{0, 0, 0, 9}); err == nil {
+		t.Error("expected DecodeError() to reject a short body")
+	}
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// A denied fire-and-forget command travels as the payload of knet.CmdPermissionDenied:
+//
+//	denied: [4 bytes: CommandID][N bytes: UTF-8 permission]
+
+const deniedHeaderSize = 4
+
+// EncodeDenied builds the body of a permission denial for commandID.
+func EncodeDenied(commandID uint32, permission string) []byte {
+	out := make([]byte, deniedHeaderSize+len(permission))
+	binary.BigEndian.PutUint32(out[0:4], commandID)
+	copy(out[deniedHeaderSize:], permission)
+	return out
+}
+
+// DecodeDenied splits a permission denial body into its command ID and permission.
+func DecodeDenied(data []byte) (commandID uint32, permission string, err error) {
+	if len(data) < deniedHeaderSize {
+		return 0, "", errors.New("denial too short")
+	}
+	return binary.BigEndian.Uint32(data[0:4]), string(data[deniedHeaderSize:]), nil
+}
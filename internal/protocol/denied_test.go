@@ -0,0 +1,20 @@
+package protocol
+
+import "testing"
+
+// TestDeniedRoundTrip tests encoding and decoding of permission denials
+func TestDeniedRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	commandID, permission, err := DecodeDenied(EncodeDenied(0x0200, "role:admin"))
+	if err != nil {
+		t.Fatalf("DecodeDenied() error = %v", err)
+	}
+	if commandID != 0x0200 || permission != "role:admin" {
+		t.Errorf("DecodeDenied() = (0x%x, %q), want (0x200, %q)", commandID, permission, "role:admin")
+	}
+
+	if _, _, err := DecodeDenied([]byte{0, 2}); err == nil {
+		t.Error("expected DecodeDenied() to reject a short body")
+	}
+}
@@ -0,0 +1,179 @@
+// Package schema derives JSON Schemas from Go types, following the rules
+// encoding/json uses to encode them.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luciancaetano/knet"
+)
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Field is an encoded struct field
+type Field struct {
+	Name     string
+	Required bool
+	Schema   knet.Schema
+}
+
+// Generator builds schemas, collecting named struct types as definitions
+// referenced with "$ref": refPrefix + name.
+type Generator struct {
+	refPrefix   string
+	definitions map[string]knet.Schema
+	names       map[reflect.Type]string
+}
+
+// NewGenerator returns a generator whose references start with refPrefix,
+// such as "#/components/schemas/".
+func NewGenerator(refPrefix string) *Generator {
+	return &Generator{
+		refPrefix:   refPrefix,
+		definitions: make(map[string]knet.Schema),
+		names:       make(map[reflect.Type]string),
+	}
+}
+
+// Definitions returns the named types referenced so far, nil when there are none
+func (g *Generator) Definitions() map[string]knet.Schema {
+	if len(g.definitions) == 0 {
+		return nil
+	}
+	return g.definitions
+}
+
+// Schema returns the schema of t
+func (g *Generator) Schema(t reflect.Type) knet.Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return knet.Schema{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return knet.Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Custom encodings cannot be inferred
+		return knet.Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return knet.Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return knet.Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return knet.Schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return knet.Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return knet.Schema{"type": "number"}
+	case reflect.String:
+		return knet.Schema{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return knet.Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return knet.Schema{"type": "array", "items": g.Schema(t.Elem())}
+	case reflect.Array:
+		return knet.Schema{"type": "array", "items": g.Schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return knet.Schema{"type": "object", "additionalProperties": g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return knet.Schema{"$ref": g.refPrefix + g.define(t)}
+	default:
+		// Interfaces accept any value; channels and functions cannot be encoded
+		return knet.Schema{}
+	}
+}
+
+// Fields returns the encoded fields of struct type t, in declaration order
+func (g *Generator) Fields(t reflect.Type) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// Untagged embedded structs are flattened, like encoding/json does
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, g.Fields(embedded)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, Field{
+			Name:     name,
+			Required: !strings.Contains(","+opts+",", ",omitempty,") && !strings.Contains(","+opts+",", ",omitzero,"),
+			Schema:   g.Schema(f.Type),
+		})
+	}
+	return fields
+}
+
+// structSchema returns the inline object schema of struct type t
+func (g *Generator) structSchema(t reflect.Type) knet.Schema {
+	properties := knet.Schema{}
+	var required []string
+	for _, f := range g.Fields(t) {
+		properties[f.Name] = f.Schema
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+
+	s := knet.Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// define registers named struct type t as a definition and returns its name.
+// Types sharing a name across packages get a numeric suffix.
+func (g *Generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	for i := 2; g.definitions[name] != nil; i++ {
+		name = t.Name() + "_" + strconv.Itoa(i)
+	}
+
+	// Register before recursing so self-referencing types terminate
+	g.names[t] = name
+	g.definitions[name] = knet.Schema{}
+	for k, v := range g.structSchema(t) {
+		g.definitions[name][k] = v
+	}
+	return name
+}
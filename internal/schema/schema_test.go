@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type node struct {
+	Value    int     `json:"value"`
+	Children []*node `json:"children,omitempty"`
+}
+
+type sample struct {
+	base
+	Name      string            `json:"name"`
+	Nickname  *string           `json:"nickname,omitempty"`
+	Tags      []string          `json:"tags"`
+	Counts    map[string]uint16 `json:"counts"`
+	Avatar    []byte            `json:"avatar"`
+	At        time.Time         `json:"at"`
+	Extra     json.RawMessage   `json:"extra"`
+	Position  [2]float64        `json:"position"`
+	Tree      node              `json:"tree"`
+	Untagged  bool
+	Skipped   string `json:"-"`
+	unexposed int
+}
+
+// TestSchema tests the schema derived for each kind of field
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	gen := NewGenerator("#/defs/")
+	got := gen.Schema(reflect.TypeFor[*sample]())
+	if got["$ref"] != "#/defs/sample" {
+		t.Fatalf("Schema() = %v, want a reference to sample", got)
+	}
+
+	defs := gen.Definitions()
+	encoded, _ := json.Marshal(defs["sample"])
+	want := `{"properties":{` +
+		`"Untagged":{"type":"boolean"},` +
+		`"at":{"format":"date-time","type":"string"},` +
+		`"avatar":{"contentEncoding":"base64","type":"string"},` +
+		`"counts":{"additionalProperties":{"minimum":0,"type":"integer"},"type":"object"},` +
+		`"extra":{},` +
+		`"id":{"type":"string"},` +
+		`"name":{"type":"string"},` +
+		`"nickname":{"type":"string"},` +
+		`"position":{"items":{"type":"number"},"maxItems":2,"minItems":2,"type":"array"},` +
+		`"tags":{"items":{"type":"string"},"type":"array"},` +
+		`"tree":{"$ref":"#/defs/node"}},` +
+		`"required":["id","name","tags","counts","avatar","at","extra","position","tree","Untagged"],` +
+		`"type":"object"}`
+	if string(encoded) != want {
+		t.Errorf("sample schema =\n%s\nwant\n%s", encoded, want)
+	}
+
+	// Recursive types terminate with a self reference
+	encoded, _ = json.Marshal(defs["node"])
+	want = `{"properties":{"children":{"items":{"$ref":"#/defs/node"},"type":"array"},"value":{"type":"integer"}},"required":["value"],"type":"object"}`
+	if string(encoded) != want {
+		t.Errorf("node schema =\n%s\nwant\n%s", encoded, want)
+	}
+}
+
+// TestSchemaAnonymousStruct tests that unnamed structs are inlined
+func TestSchemaAnonymousStruct(t *testing.T) {
+	t.Parallel()
+
+	gen := NewGenerator("#/defs/")
+	got := gen.Schema(reflect.TypeFor[struct {
+		OK bool `json:"ok"`
+	}]())
+
+	encoded, _ := json.Marshal(got)
+	if want := `{"properties":{"ok":{"type":"boolean"}},"required":["ok"],"type":"object"}`; string(encoded) != want {
+		t.Errorf("Schema() = %s, want %s", encoded, want)
+	}
+	if gen.Definitions() != nil {
+		t.Errorf("Definitions() = %v, want nil", gen.Definitions())
+	}
+}
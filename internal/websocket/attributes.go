@@ -0,0 +1,51 @@
+package websocket
+
+// Set stores value under key in the client's attribute store
+func (c *Client) Set(key string, value any) {
+	c.attrsMu.Lock()
+	defer c.attrsMu.Unlock()
+
+	if c.attrs == nil {
+		c.attrs = make(map[string]any)
+	}
+	c.attrs[key] = value
+}
+
+// Get returns the value stored under key
+func (c *Client) Get(key string) (any, bool) {
+	c.attrsMu.RLock()
+	defer c.attrsMu.RUnlock()
+
+	value, ok := c.attrs[key]
+	return value, ok
+}
+
+// Delete removes the value stored under key. Deleting a missing key is a no-op.
+func (c *Client) Delete(key string) {
+	c.attrsMu.Lock()
+	defer c.attrsMu.Unlock()
+
+	delete(c.attrs, key)
+}
+
+// clearAttributes drops every stored value. It is called when the client disconnects.
+func (c *Client) clearAttributes() {
+	c.attrsMu.Lock()
+	defer c.attrsMu.Unlock()
+
+	c.attrs = nil
+}
+
+// Principal returns the identity attached to the client by authentication
+func (c *Client) Principal() any {
+	c.attrsMu.RLock()
+	defer c.attrsMu.RUnlock()
+	return c.principal
+}
+
+// setPrincipal attaches the identity established by in-band authentication
+func (c *Client) setPrincipal(principal any) {
+	c.attrsMu.Lock()
+	defer c.attrsMu.Unlock()
+	c.principal = principal
+}
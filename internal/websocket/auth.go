@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+)
+
+// DefaultAuthTimeout is how long a client has to authenticate in-band when
+// InBandAuthConfig.Timeout is zero
+const DefaultAuthTimeout = 10 * time.Second
+
+// ValidateCredentialsFn approves the credentials a client sent in-band and
+// returns its principal (see knet.Client.Principal). Returning an error closes
+// the connection with ClosePolicyViolation.
+//
+// credentials is the payload of the auth command, or the raw params of the
+// auth JSON-RPC method. ctx is cancelled when the authentication deadline
+// passes or the client disconnects.
+//
+// Note: This function is called synchronously on the client's read loop.
+type ValidateCredentialsFn = func(ctx context.Context, client knet.Client, credentials []byte) (principal any, err error)
+
+// InBandAuthConfig enables an unauthenticated phase at the start of every
+// connection, for clients such as browsers that cannot send credentials with
+// the upgrade request.
+//
+// Until Validate approves the credentials, only the auth command (or JSON-RPC
+// method) is accepted; any other message closes the connection with
+// ClosePolicyViolation, as does failing to authenticate within Timeout.
+// Clients that already got a principal from ServerConfig.Authenticate skip
+// this phase.
+type InBandAuthConfig struct {
+	// CommandID is the command that carries the credentials. On success the
+	// server answers with the same command and an empty payload.
+	CommandID uint32
+	// Method is a JSON-RPC method that carries the credentials as params,
+	// answered with result true on success. Optional.
+	Method string
+	// Timeout is how long a client has to authenticate. Defaults to DefaultAuthTimeout.
+	Timeout time.Duration
+	// Validate approves or rejects the credentials
+	Validate ValidateCredentialsFn
+}
+
+// startAuth opens the unauthenticated phase of a client when in-band
+// authentication is enabled. The returned function stops the deadline timer.
+func (s *Server) startAuth(client *Client) func() {
+	if s.inBandAuth == nil || client.Principal() != nil {
+		client.authenticated.Store(true)
+		return func() {}
+	}
+
+	timeout := s.inBandAuth.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAuthTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(client.Context(), timeout)
+	client.authCtx = ctx
+	timer := time.AfterFunc(timeout, func() {
+		if !client.authenticated.Load() {
+			client.CloseWithCode(context.Background(), websocket.ClosePolicyViolation, knet.ErrAuthenticationTimeout)
+		}
+	})
+
+	return func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// handleAuthMessage handles a message read before the client authenticated.
+// Only the auth command and the auth JSON-RPC method are accepted.
+func (s *Server) handleAuthMessage(client *Client, frame *knet.Message) {
+	cfg := s.inBandAuth
+
+	switch {
+	case frame.CommandID == cfg.CommandID:
+		if s.validateCredentials(client, frame.Payload) {
+			client.Send(context.Background(), cfg.CommandID, nil)
+		}
+		return
+
+	case frame.CommandID == knet.CmdJSONRPC && cfg.Method != "":
+		var req JSONRPCRequest
+		if err := json.Unmarshal(frame.Payload, &req); err == nil && req.Method == cfg.Method {
+			if s.validateCredentials(client, req.Params) {
+				response, _ := json.Marshal(JSONRPCResponse{JSONRPC: knet.JSONRPCVersion, Result: json.RawMessage("true"), ID: req.ID})
+				client.Send(context.Background(), knet.CmdJSONRPC, response)
+			}
+			return
+		}
+	}
+
+	client.CloseWithCode(context.Background(), websocket.ClosePolicyViolation, knet.ErrAuthenticationRequired)
+}
+
+// validateCredentials validates credentials and marks the client authenticated, or
+// closes it when they are rejected
+func (s *Server) validateCredentials(client *Client, credentials []byte) bool {
+	principal, err := s.inBandAuth.Validate(client.authCtx, client, credentials)
+	if err != nil {
+		client.CloseWithCode(context.Background(), websocket.ClosePolicyViolation, knet.ErrAuthenticationFailed)
+		return false
+	}
+
+	client.setPrincipal(principal)
+	client.authenticated.Store(true)
+	return true
+}
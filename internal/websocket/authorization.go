@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+)
+
+// OnPermissionDeniedFn is the audit hook called for every message denied
+// because the client lacks a permission, whether by RequirePermissions or by
+// a middleware or handler returning *knet.PermissionError.
+//
+// The callback runs on the handler's goroutine, before the denial is sent.
+type OnPermissionDeniedFn = func(err *knet.PermissionError)
+
+// authorize checks that the client holds every permission the entry requires
+func (s *Server) authorize(client *Client, msg *knet.Message, entry *handlerEntry) error {
+	for _, permission := range entry.options.Permissions {
+		if !knet.HasPermission(client, permission) {
+			return &knet.PermissionError{
+				ClientID:   client.ID(),
+				CommandID:  msg.CommandID,
+				Method:     msg.Method,
+				Permission: permission,
+			}
+		}
+	}
+	return nil
+}
+
+// permissionDenied reports err to the audit hook when it is a permission
+// denial, filling in the client and message it was returned for
+func (s *Server) permissionDenied(client *Client, msg *knet.Message, err error) (*knet.PermissionError, bool) {
+	var permErr *knet.PermissionError
+	if !errors.As(err, &permErr) {
+		return nil, false
+	}
+
+	denied := *permErr
+	if denied.ClientID == "" {
+		denied.ClientID = client.ID()
+		denied.CommandID = msg.CommandID
+		denied.Method = msg.Method
+	}
+
+	if s.onPermissionDenied != nil {
+		s.onPermissionDenied(&denied)
+	} else {
+		fmt.Printf("Warn: %v client_id=%s command_id=0x%08X method=%q\n", &denied, denied.ClientID, denied.CommandID, denied.Method)
+	}
+	return &denied, true
+}
+
+// sendPermissionDenied answers a denied fire-and-forget command with CmdPermissionDenied
+func (s *Server) sendPermissionDenied(client *Client, err *knet.PermissionError) {
+	client.Send(context.Background(), knet.CmdPermissionDenied, protocol.EncodeDenied(err.CommandID, err.Permission))
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+)
+
+// broadcast queues one frame for every client and reports the outcome.
+//
+// The frame is encoded once and shared as a prepared message. Clients are
+// first offered the frame without waiting; only clients whose full queue uses
+// SendQueueBlock are then waited on, in parallel and bounded by ctx, so a slow
+// client never delays delivery to the others.
+func (s *Server) broadcast(ctx context.Context, clients []*Client, commandID uint32, payload []byte, opts []knet.BroadcastOption) (*knet.BroadcastReport, error) {
+	var options knet.BroadcastOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	data, err := protocol.Encode(commandID, payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", knet.ErrFailedToEncode, err)
+	}
+	prepared, err := websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", knet.ErrFailedToEncode, err)
+	}
+	msg := outboundMessage{command: commandID, prepared: prepared}
+
+	report := &knet.BroadcastReport{}
+	if options.ClientErrors {
+		report.Errors = make(map[string]error)
+	}
+
+	var mu sync.Mutex
+	record := func(client *Client, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case err == nil:
+			report.Delivered++
+			return
+		case !client.IsAlive():
+			report.Closed++
+		default:
+			report.Dropped++
+		}
+		if report.Errors != nil {
+			report.Errors[client.ID()] = err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		err := client.enqueue(ctx, msg, false)
+		if err == errWouldBlock {
+			wg.Add(1)
+			go func(client *Client) {
+				defer wg.Done()
+				record(client, client.enqueue(ctx, msg, true))
+			}(client)
+			continue
+		}
+		record(client, err)
+	}
+	wg.Wait()
+
+	return report, nil
+}
+
+// BroadcastWhere sends a command to every connected client for which match returns true
+func (s *Server) BroadcastWhere(ctx context.Context, commandID uint32, payload []byte, match func(client knet.Client) bool, opts ...knet.BroadcastOption) (*knet.BroadcastReport, error) {
+	return s.broadcast(ctx, s.connectedClients(match), commandID, payload, opts)
+}
+
+// BroadcastExcept sends a command to every connected client except the given client IDs.
+// The report always includes per-client errors.
+func (s *Server) BroadcastExcept(ctx context.Context, commandID uint32, payload []byte, exclude ...string) (*knet.BroadcastReport, error) {
+	return s.broadcast(ctx, s.connectedClients(excluding(exclude)), commandID, payload, []knet.BroadcastOption{knet.WithClientErrors()})
+}
+
+// connectedClients returns a snapshot of the connected clients accepted by
+// match, or of every connected client when match is nil
+func (s *Server) connectedClients(match func(client knet.Client) bool) []*Client {
+	var clients []*Client
+	s.clients.Range(func(key, value interface{}) bool {
+		if client, ok := value.(*Client); ok && (match == nil || match(client)) {
+			clients = append(clients, client)
+		}
+		return true
+	})
+	return clients
+}
+
+// excluding returns a predicate rejecting the given client IDs
+func excluding(ids []string) func(client knet.Client) bool {
+	skip := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		skip[id] = struct{}{}
+	}
+	return func(client knet.Client) bool {
+		_, excluded := skip[client.ID()]
+		return !excluded
+	}
+}
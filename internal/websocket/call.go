@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+)
+
+// callResult is the outcome of a server-initiated call
+type callResult struct {
+	payload []byte
+	err     error
+}
+
+// callKey identifies a pending call by the command its request was sent with
+// (CmdRequest or CmdJSONRPC) and its ID, so a reply of one kind can never
+// resolve a call of the other
+type callKey struct {
+	command uint32
+	id      uint32
+}
+
+// Call sends a correlated request to the client and waits for its reply.
+// The request ID is allocated per client; the reply is routed back by the
+// server's read loop.
+func (c *Client) Call(ctx context.Context, command uint32, payload []byte) ([]byte, error) {
+	requestID := c.nextCallID.Add(1)
+	return c.roundTrip(ctx, requestID, knet.CmdRequest, protocol.EncodeRequest(command, requestID, payload))
+}
+
+// NotifyJSONRPC sends a JSON-RPC notification, a request without an id, to the client
+func (c *Client) NotifyJSONRPC(ctx context.Context, method string, params any) error {
+	request, err := encodeJSONRPCRequest(method, params, nil)
+	if err != nil {
+		return err
+	}
+	return c.Send(ctx, knet.CmdJSONRPC, request)
+}
+
+// CallJSONRPC sends a JSON-RPC request to the client and decodes the result
+// of its response into result (which may be nil). Only a JSON-RPC response
+// with the request's id resolves the call.
+func (c *Client) CallJSONRPC(ctx context.Context, method string, params, result any) error {
+	requestID := c.nextCallID.Add(1)
+	request, err := encodeJSONRPCRequest(method, params, json.RawMessage(strconv.FormatUint(uint64(requestID), 10)))
+	if err != nil {
+		return err
+	}
+
+	raw, err := c.roundTrip(ctx, requestID, knet.CmdJSONRPC, request)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// roundTrip registers a pending call, sends its request frame and waits for
+// the reply routed back by resolveCall
+func (c *Client) roundTrip(ctx context.Context, requestID uint32, command uint32, body []byte) ([]byte, error) {
+	resultCh := make(chan callResult, 1)
+
+	c.callsMu.Lock()
+	if c.calls == nil {
+		c.callsMu.Unlock()
+		return nil, fmt.Errorf(knet.ErrConnectionClosed)
+	}
+	key := callKey{command: command, id: requestID}
+	c.calls[key] = resultCh
+	c.callsMu.Unlock()
+
+	defer func() {
+		c.callsMu.Lock()
+		if c.calls != nil {
+			delete(c.calls, key)
+		}
+		c.callsMu.Unlock()
+	}()
+
+	if err := c.Send(ctx, command, body); err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-resultCh:
+		return result.payload, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, fmt.Errorf(knet.ErrConnectionClosed)
+	}
+}
+
+// encodeJSONRPCRequest encodes a server-initiated request, or a notification
+// when id is nil. Params must encode to an object or an array; nil omits them.
+func encodeJSONRPCRequest(method string, params any, id json.RawMessage) ([]byte, error) {
+	req := JSONRPCRequest{JSONRPC: knet.JSONRPCVersion, Method: method, ID: id}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", knet.ErrInvalidParams, err)
+		}
+		if len(encoded) == 0 || (encoded[0] != '{' && encoded[0] != '[') {
+			if !bytes.Equal(encoded, jsonNull) {
+				return nil, fmt.Errorf("%s: params must be a JSON object or array", knet.ErrInvalidParams)
+			}
+			encoded = nil
+		}
+		req.Params = encoded
+	}
+	return json.Marshal(req)
+}
+
+// resolveCall delivers the reply for a pending call. Replies to unknown or
+// already abandoned calls are dropped.
+func (c *Client) resolveCall(key callKey, result callResult) {
+	c.callsMu.Lock()
+	resultCh, ok := c.calls[key]
+	if ok {
+		delete(c.calls, key)
+	}
+	c.callsMu.Unlock()
+
+	if ok {
+		resultCh <- result
+	}
+}
+
+// failPendingCalls aborts every pending call with a connection closed error
+// and rejects new ones. It is called when the client disconnects.
+func (c *Client) failPendingCalls() {
+	c.callsMu.Lock()
+	calls := c.calls
+	c.calls = nil
+	c.callsMu.Unlock()
+
+	for _, resultCh := range calls {
+		resultCh <- callResult{err: fmt.Errorf(knet.ErrConnectionClosed)}
+	}
+}
+
+// handleResponseMessage routes a CmdResponse frame to the pending call
+func (s *Server) handleResponseMessage(client *Client, body []byte) {
+	requestID, payload, err := protocol.DecodeResponse(body)
+	if err != nil {
+		client.CloseWithCode(context.Background(), websocket.CloseProtocolError, knet.ErrInvalidMessageFormat)
+		return
+	}
+	client.resolveCall(callKey{command: knet.CmdRequest, id: requestID}, callResult{payload: payload})
+}
+
+// handleResponseErrorMessage routes a CmdResponseError frame to the pending call
+func (s *Server) handleResponseErrorMessage(client *Client, body []byte) {
+	requestID, code, message, err := protocol.DecodeError(body)
+	if err != nil {
+		client.CloseWithCode(context.Background(), websocket.CloseProtocolError, knet.ErrInvalidMessageFormat)
+		return
+	}
+	client.resolveCall(callKey{command: knet.CmdRequest, id: requestID}, callResult{err: &knet.RequestError{Code: code, Message: message}})
+}
+
+// handleJSONRPCResponse routes a JSON-RPC response to the pending call made
+// with CallJSONRPC and reports whether the frame was a response. Requests and
+// batches are left to handleJSONRPCMessage; responses are never answered, so
+// ones for unknown or abandoned calls are dropped.
+func (s *Server) handleJSONRPCResponse(client *Client, body []byte) bool {
+	var response struct {
+		Method json.RawMessage `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *knet.RPCError  `json:"error"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Method != nil {
+		return false
+	}
+	if response.Result == nil && response.Error == nil {
+		return false
+	}
+
+	requestID, err := strconv.ParseUint(string(response.ID), 10, 32)
+	if err != nil {
+		return true
+	}
+	key := callKey{command: knet.CmdJSONRPC, id: uint32(requestID)}
+	if response.Error != nil {
+		client.resolveCall(key, callResult{err: response.Error})
+		return true
+	}
+	client.resolveCall(key, callResult{payload: response.Result})
+	return true
+}
@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+)
+
+// DispatchMode selects how handlers are scheduled after a message is read
+type DispatchMode int
+
+const (
+	// DispatchUnbounded runs every handler in its own goroutine (default).
+	// Handlers of one client may run concurrently and out of order.
+	DispatchUnbounded DispatchMode = iota
+	// DispatchWorkerPool runs handlers on a fixed pool of workers shared by all
+	// clients, fed by a single bounded queue.
+	DispatchWorkerPool
+	// DispatchPerClient runs the handlers of each client one at a time, in
+	// arrival order, with a bounded queue per client.
+	DispatchPerClient
+)
+
+// QueueFullPolicy decides what happens to a message whose dispatch queue is full
+type QueueFullPolicy int
+
+const (
+	// QueueFullBlock stops reading from the client until the queue has room (default).
+	// Pings are not answered while blocked, so keep handlers shorter than the read deadline.
+	QueueFullBlock QueueFullPolicy = iota
+	// QueueFullDrop discards the message. Requests and JSON-RPC calls get no reply.
+	QueueFullDrop
+	// QueueFullDisconnect closes the client with CloseTryAgainLater
+	QueueFullDisconnect
+)
+
+// Default sizes used when DispatchConfig leaves them at zero
+const (
+	DefaultDispatchWorkers   = 64
+	DefaultDispatchQueueSize = 256
+)
+
+// DispatchConfig defines how handlers are scheduled.
+// Fire-and-forget commands, correlated requests and JSON-RPC calls are all
+// dispatched this way; subscriptions and call responses are always handled inline.
+type DispatchConfig struct {
+	// Mode selects unbounded goroutines, a worker pool or per-client ordering
+	Mode DispatchMode
+	// Workers is the pool size for DispatchWorkerPool. Defaults to DefaultDispatchWorkers.
+	Workers int
+	// QueueSize is the number of messages waiting for a handler: shared by the
+	// pool, or per client for DispatchPerClient. Defaults to DefaultDispatchQueueSize.
+	QueueSize int
+	// QueueFullPolicy applies when the queue is full
+	QueueFullPolicy QueueFullPolicy
+}
+
+// normalize fills in default sizes
+func (cfg DispatchConfig) normalize() DispatchConfig {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultDispatchWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultDispatchQueueSize
+	}
+	return cfg
+}
+
+// workerPool is the shared queue and workers of DispatchWorkerPool.
+// Workers start on first use and exit when Stop closes the queue; the next
+// message starts them again.
+type workerPool struct {
+	// Held for reading while a task is being queued, so stop never closes
+	// the queue under a sender
+	mu        sync.RWMutex
+	workers   int
+	queueSize int
+	queue     chan func() // nil while stopped
+}
+
+func newWorkerPool(workers, queueSize int) *workerPool {
+	return &workerPool{workers: workers, queueSize: queueSize}
+}
+
+// acquire returns the queue, starting the workers if needed.
+// The caller must call release once it is done sending.
+func (p *workerPool) acquire() chan func() {
+	p.mu.RLock()
+	for p.queue == nil {
+		p.mu.RUnlock()
+		p.start()
+		p.mu.RLock()
+	}
+	return p.queue
+}
+
+// release ends a send started with acquire
+func (p *workerPool) release() {
+	p.mu.RUnlock()
+}
+
+// start creates the queue and launches the workers, unless they are running
+func (p *workerPool) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.queue != nil {
+		return
+	}
+	p.queue = make(chan func(), p.queueSize)
+	for i := 0; i < p.workers; i++ {
+		go runQueue(p.queue)
+	}
+}
+
+// stop closes the queue; workers exit once the tasks already queued have run
+func (p *workerPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.queue != nil {
+		close(p.queue)
+		p.queue = nil
+	}
+}
+
+// runQueue runs tasks until the queue is closed
+func runQueue(queue <-chan func()) {
+	for task := range queue {
+		task()
+	}
+}
+
+// startClientQueue creates the ordered queue of a client in DispatchPerClient
+// mode. The returned function closes it once the read loop has exited; tasks
+// already queued still run.
+func (s *Server) startClientQueue(client *Client) func() {
+	if s.dispatch.Mode != DispatchPerClient {
+		return func() {}
+	}
+
+	client.queue = make(chan func(), s.dispatch.QueueSize)
+	go runQueue(client.queue)
+	return func() { close(client.queue) }
+}
+
+// schedule runs task according to the dispatch mode. It is only called from
+// the client's read loop. Scheduled tasks are counted as in flight until they
+// finish, so Drain can wait for them.
+func (s *Server) schedule(client *Client, task func()) {
+	s.inflight.Add(1)
+	run := func() {
+		defer s.inflight.Add(-1)
+		task()
+	}
+
+	var queue chan func()
+	switch s.dispatch.Mode {
+	case DispatchWorkerPool:
+		queue = s.pool.acquire()
+		defer s.pool.release()
+	case DispatchPerClient:
+		queue = client.queue
+	default:
+		go run()
+		return
+	}
+
+	select {
+	case queue <- run:
+		return
+	default:
+	}
+
+	switch s.dispatch.QueueFullPolicy {
+	case QueueFullDrop:
+		s.inflight.Add(-1)
+		fmt.Printf("Warn: Dispatch queue full, message dropped client_id=%s remote_addr=%s\n", client.ID(), client.RemoteAddr())
+	case QueueFullDisconnect:
+		s.inflight.Add(-1)
+		fmt.Printf("Warn: Dispatch queue full, closing client client_id=%s remote_addr=%s\n", client.ID(), client.RemoteAddr())
+		client.CloseWithCode(context.Background(), websocket.CloseTryAgainLater, knet.ErrDispatchQueueFull)
+	default:
+		select {
+		case queue <- run:
+		case <-client.Context().Done():
+			s.inflight.Add(-1)
+		}
+	}
+}
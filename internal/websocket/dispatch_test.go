@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestWorkerPoolStop tests that stop ends the workers after running queued
+// tasks, and that the next task starts them again.
+// Not parallel, so the goroutine count only reflects the pool.
+func TestWorkerPoolStop(t *testing.T) {
+	const workers = 100
+	before := runtime.NumGoroutine()
+	pool := newWorkerPool(workers, 10)
+
+	ran := make(chan struct{}, 2)
+	for cycle := 0; cycle < 2; cycle++ {
+		queue := pool.acquire()
+		queue <- func() { ran <- struct{}{} }
+		pool.release()
+
+		if n := runtime.NumGoroutine(); n < before+workers {
+			t.Fatalf("cycle %d: %d goroutines, want at least %d workers running", cycle, n, workers)
+		}
+		pool.stop()
+
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatalf("cycle %d: task queued before stop did not run", cycle)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after stop, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
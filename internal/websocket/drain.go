@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+)
+
+// drainPollInterval is how often Drain checks for in-flight work
+const drainPollInterval = 10 * time.Millisecond
+
+// Drain gracefully shuts the server down. It refuses new connections with
+// 503 Service Unavailable, sends CmdGoingAway (payload: hint) to every client,
+// waits until in-flight handlers have finished and queued messages have been
+// written, closes every client with CloseGoingAway and finally calls Stop.
+//
+// Messages clients send while the server drains are still handled. When ctx
+// expires before the server is idle, clients are closed anyway and ctx.Err()
+// is returned.
+func (s *Server) Drain(ctx context.Context, hint string) error {
+	s.draining.Store(true)
+
+	// Fan out like a broadcast, so a client with a full queue does not keep
+	// the notice from the others
+	s.broadcast(ctx, s.connectedClients(nil), knet.CmdGoingAway, []byte(hint), nil)
+
+	err := s.waitIdle(ctx)
+
+	// Close in parallel: each close frame may wait up to a second on a stuck writer
+	var wg sync.WaitGroup
+	for _, client := range s.connectedClients(nil) {
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			client.CloseWithCode(ctx, websocket.CloseGoingAway, knet.ErrServerDraining)
+		}(client)
+	}
+	wg.Wait()
+
+	if stopErr := s.Stop(ctx); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+// waitIdle blocks until no handler is in flight and every connected client
+// has flushed its send queue, or until ctx is done
+func (s *Server) waitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for !s.idle() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// idle reports whether there is no in-flight handler and no unwritten message
+func (s *Server) idle() bool {
+	if s.inflight.Load() > 0 {
+		return false
+	}
+
+	idle := true
+	s.clients.Range(func(key, value interface{}) bool {
+		if client, ok := value.(*Client); ok && client.IsAlive() && !client.flushed() {
+			idle = false
+		}
+		return idle
+	})
+	return idle
+}
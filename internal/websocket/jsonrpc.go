@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/luciancaetano/knet"
+)
+
+// JSONRPCRequest represents a JSON-RPC 2.0 request
+// Params are kept raw and decoded by the method's handler adapter.
+// ID is nil for notifications, and the JSON literal null for requests with a null id.
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// JSONRPCResponse represents a JSON-RPC 2.0 response
+// Result holds the encoded result; it is always set (possibly to null) on success
+// and nil on error, so exactly one of result and error is sent.
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSONRPCError represents a JSON-RPC 2.0 error
+// Handlers and middleware may return it to choose the error code
+type JSONRPCError = knet.RPCError
+
+// JSONRPCErrorMapperFn translates an error returned by a JSON-RPC handler into
+// the error sent to the client. It is called for params errors and for errors
+// that are not already a *knet.RPCError or a permission denial; recovered
+// panics always send Internal error. Returning nil keeps the default response:
+// Invalid params for a *knet.ParamsError, a generic Internal error otherwise.
+type JSONRPCErrorMapperFn = func(client knet.Client, method string, err error) *knet.RPCError
+
+// jsonNull is the id of responses to requests whose id could not be determined
+var jsonNull = json.RawMessage("null")
+
+// handleJSONRPCMessage handles a JSON-RPC frame holding a single request or a batch.
+// Batch entries are processed in order; notifications are never answered, and a
+// batch made only of notifications gets no response at all.
+func (s *Server) handleJSONRPCMessage(client *Client, frame *knet.Message) {
+	payload := bytes.TrimLeft(frame.Payload, " \t\r\n")
+	if !json.Valid(payload) {
+		s.sendJSONRPC(client, newJSONRPCError(jsonNull, knet.JSONRPCParseError, knet.ErrParseError, nil))
+		return
+	}
+
+	if payload[0] != '[' {
+		if response := s.callJSONRPC(client, frame, payload); response != nil {
+			s.sendJSONRPC(client, response)
+		}
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(payload, &batch); err != nil || len(batch) == 0 {
+		s.sendJSONRPC(client, newJSONRPCError(jsonNull, knet.JSONRPCInvalidRequest, knet.ErrInvalidRequest, nil))
+		return
+	}
+
+	responses := make([]*JSONRPCResponse, 0, len(batch))
+	for _, raw := range batch {
+		if response := s.callJSONRPC(client, frame, raw); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) > 0 {
+		s.sendJSONRPC(client, responses)
+	}
+}
+
+// callJSONRPC runs a single request object and returns its response,
+// or nil when the request is a notification
+func (s *Server) callJSONRPC(client *Client, frame *knet.Message, raw json.RawMessage) *JSONRPCResponse {
+	req, errResponse := parseJSONRPCRequest(raw)
+	if errResponse != nil {
+		return errResponse
+	}
+	notification := req.ID == nil
+
+	entry, ok := s.jsonRPCHandlers.Load(req.Method)
+	if !ok {
+		if notification {
+			return nil
+		}
+		return newJSONRPCError(req.ID, knet.JSONRPCMethodNotFound, knet.ErrMethodNotFound, nil)
+	}
+
+	msg := &knet.Message{
+		Kind:       knet.KindJSONRPC,
+		CommandID:  knet.CmdJSONRPC,
+		Method:     req.Method,
+		Payload:    req.Params,
+		Sequence:   frame.Sequence,
+		ReceivedAt: frame.ReceivedAt,
+	}
+	result, err := s.invoke(client, msg, entry.(*handlerEntry))
+
+	if notification {
+		// There is no response to carry errors, so report them like
+		// fire-and-forget handler errors
+		if _, denied := s.permissionDenied(client, msg, err); !denied && err != nil && err != errHandlerPanicked {
+			s.reportHandlerError(&knet.HandlerError{ClientID: client.ID(), CommandID: knet.CmdJSONRPC, Method: req.Method, Err: err})
+		}
+		return nil
+	}
+
+	if err != nil {
+		return s.jsonRPCErrorResponse(client, msg, req.ID, err)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return newJSONRPCError(req.ID, knet.JSONRPCInternalError, knet.ErrInternalError, nil)
+	}
+	return &JSONRPCResponse{JSONRPC: knet.JSONRPCVersion, Result: encoded, ID: req.ID}
+}
+
+// parseJSONRPCRequest validates a request object. Members are checked one by
+// one so a wrongly typed member is reported as Invalid Request, not Parse error.
+func parseJSONRPCRequest(raw json.RawMessage) (*JSONRPCRequest, *JSONRPCResponse) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, newJSONRPCError(jsonNull, knet.JSONRPCInvalidRequest, knet.ErrInvalidRequest, nil)
+	}
+
+	req := &JSONRPCRequest{}
+	if id, ok := members["id"]; ok {
+		if !validJSONRPCID(id) {
+			return nil, newJSONRPCError(jsonNull, knet.JSONRPCInvalidRequest, knet.ErrInvalidRequest, nil)
+		}
+		req.ID = id
+	}
+
+	invalid := func() *JSONRPCResponse {
+		id := req.ID
+		if id == nil {
+			id = jsonNull
+		}
+		return newJSONRPCError(id, knet.JSONRPCInvalidRequest, knet.ErrInvalidRequest, nil)
+	}
+
+	if err := json.Unmarshal(members["jsonrpc"], &req.JSONRPC); err != nil || req.JSONRPC != knet.JSONRPCVersion {
+		return nil, invalid()
+	}
+	if err := json.Unmarshal(members["method"], &req.Method); err != nil || req.Method == "" {
+		return nil, invalid()
+	}
+	if params, ok := members["params"]; ok {
+		// Params must be structured: an object (named) or an array (positional)
+		if len(params) == 0 || (params[0] != '{' && params[0] != '[') {
+			return nil, invalid()
+		}
+		req.Params = params
+	}
+	return req, nil
+}
+
+// validJSONRPCID reports whether id is a string, a number or null
+func validJSONRPCID(id json.RawMessage) bool {
+	if len(id) == 0 {
+		return false
+	}
+	switch c := id[0]; {
+	case c == '"', c == '-', c >= '0' && c <= '9':
+		return true
+	default:
+		return bytes.Equal(id, jsonNull)
+	}
+}
+
+// jsonRPCErrorResponse turns a handler error into an error response.
+// A *knet.RPCError keeps its code and data; permission denials use
+// JSONRPCPermissionDenied. Other errors go through the error mapper, and are
+// otherwise answered with JSONRPCInvalidParams for params errors or sanitized
+// to Internal error.
+// Recovered panics, already reported, are always an Internal error.
+func (s *Server) jsonRPCErrorResponse(client *Client, msg *knet.Message, id json.RawMessage, err error) *JSONRPCResponse {
+	if err == errHandlerPanicked {
+		return newJSONRPCError(id, knet.JSONRPCInternalError, knet.ErrInternalError, nil)
+	}
+
+	if permErr, ok := s.permissionDenied(client, msg, err); ok {
+		return newJSONRPCError(id, knet.JSONRPCPermissionDenied, knet.ErrPermissionDenied, map[string]string{"permission": permErr.Permission})
+	}
+
+	var rpcErr *knet.RPCError
+	if errors.As(err, &rpcErr) {
+		return newJSONRPCError(id, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+
+	if s.jsonRPCErrorMapper != nil {
+		if rpcErr := s.jsonRPCErrorMapper(client, msg.Method, err); rpcErr != nil {
+			return newJSONRPCError(id, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		}
+	}
+
+	var paramsErr *knet.ParamsError
+	if errors.As(err, &paramsErr) {
+		return newJSONRPCError(id, knet.JSONRPCInvalidParams, knet.ErrInvalidParams, paramsErrorData(paramsErr.Err))
+	}
+
+	// The error text may describe server internals, so report it instead of sending it
+	s.reportHandlerError(&knet.HandlerError{ClientID: client.ID(), CommandID: knet.CmdJSONRPC, Method: msg.Method, Err: err})
+	return newJSONRPCError(id, knet.JSONRPCInternalError, knet.ErrInternalError, nil)
+}
+
+// paramsErrorData locates a params decoding error without exposing Go type
+// names: the path of the offending JSON field, or the byte offset of a syntax
+// error. Returns nil when params as a whole have the wrong shape.
+func paramsErrorData(err error) any {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return nil
+		}
+		return map[string]any{"field": typeErr.Field}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return map[string]any{"offset": syntaxErr.Offset}
+	}
+	return nil
+}
+
+// newJSONRPCError builds an error response
+func newJSONRPCError(id json.RawMessage, code int, message string, data interface{}) *JSONRPCResponse {
+	return &JSONRPCResponse{
+		JSONRPC: knet.JSONRPCVersion,
+		Error:   &JSONRPCError{Code: code, Message: message, Data: data},
+		ID:      id,
+	}
+}
+
+// sendJSONRPC encodes a response (or a batch of responses) and sends it
+func (s *Server) sendJSONRPC(client *Client, response interface{}) {
+	responseData, err := json.Marshal(response)
+	if err != nil {
+		// Log error but continue - marshal errors are rare (e.g. unencodable error data)
+		fmt.Printf("Failed to marshal JSON-RPC response: %v\n", err)
+		return
+	}
+
+	if err := client.Send(context.Background(), knet.CmdJSONRPC, responseData); err != nil {
+		// Log error - client may have disconnected
+		fmt.Printf("Failed to send JSON-RPC response to client %s: %v\n", client.ID(), err)
+	}
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"context"
+
+	"github.com/luciancaetano/knet"
+)
+
+// handlerEntry is a registered handler with its per-handler options applied
+type handlerEntry struct {
+	handler knet.Handler
+	options knet.HandlerOptions
+}
+
+// newHandlerEntry applies opts and wraps h with the per-handler middleware
+func newHandlerEntry(h knet.Handler, opts []knet.HandlerOption) *handlerEntry {
+	entry := &handlerEntry{}
+	for _, opt := range opts {
+		opt(&entry.options)
+	}
+	entry.handler = knet.Chain(h, entry.options.Middleware...)
+	return entry
+}
+
+// invoke checks the entry's required permissions and runs it through the
+// server-wide middleware chain.
+// The handler context is derived from the client context, so it is cancelled
+// when the client disconnects or the server stops, and carries msg.
+// A panic anywhere in the chain is recovered and handled by recoverHandler.
+func (s *Server) invoke(client *Client, msg *knet.Message, entry *handlerEntry) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, s.recoverHandler(client, msg, p)
+		}
+	}()
+
+	ctx := knet.NewMessageContext(client.Context(), msg)
+	if entry.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, entry.options.Timeout)
+		defer cancel()
+	}
+
+	if err := s.authorize(client, msg, entry); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	middleware := s.middleware
+	s.mu.RUnlock()
+
+	return knet.Chain(entry.handler, middleware...)(ctx, client, msg)
+}
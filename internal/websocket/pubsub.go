@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/luciancaetano/knet"
+)
+
+// AuthorizeSubscribeFn decides whether a client may subscribe to a topic pattern.
+// It is called for every subscription requested by a client with CmdSubscribe;
+// return true to approve it or false to deny it. Subscriptions made on the server
+// with Subscribe bypass this hook.
+//
+// Note: This function is called synchronously on the client's read loop.
+// Avoid long-running operations.
+type AuthorizeSubscribeFn = func(client knet.Client, pattern string) bool
+
+const (
+	topicSeparator    = "."
+	wildcardToken     = "*" // matches exactly one token
+	fullWildcardToken = ">" // matches one or more trailing tokens
+)
+
+// validTopic reports whether topic is a concrete topic that can be published to:
+// non-empty dot-separated tokens without wildcards
+func validTopic(topic string) bool {
+	if topic == "" {
+		return false
+	}
+	for _, token := range strings.Split(topic, topicSeparator) {
+		if token == "" || token == wildcardToken || token == fullWildcardToken {
+			return false
+		}
+	}
+	return true
+}
+
+// validPattern reports whether pattern is a valid subscription pattern.
+// "*" may replace any single token and ">" may only appear as the last token.
+func validPattern(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	tokens := strings.Split(pattern, topicSeparator)
+	for i, token := range tokens {
+		if token == "" {
+			return false
+		}
+		if token == fullWildcardToken && i != len(tokens)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTopic reports whether a concrete topic matches a subscription pattern.
+//
+//	matchTopic("orders.eu.*", "orders.eu.created")   // true
+//	matchTopic("orders.eu.*", "orders.eu.x.created") // false
+//	matchTopic("metrics.>", "metrics.cpu.load")      // true
+//	matchTopic("metrics.>", "metrics")               // false
+func matchTopic(pattern, topic string) bool {
+	patternTokens := strings.Split(pattern, topicSeparator)
+	topicTokens := strings.Split(topic, topicSeparator)
+
+	for i, token := range patternTokens {
+		if token == fullWildcardToken {
+			return len(topicTokens) > i
+		}
+		if i >= len(topicTokens) {
+			return false
+		}
+		if token != wildcardToken && token != topicTokens[i] {
+			return false
+		}
+	}
+	return len(topicTokens) == len(patternTokens)
+}
+
+// Subscribe subscribes a connected client to a topic pattern without consulting
+// the authorization hook
+func (s *Server) Subscribe(client knet.Client, pattern string) error {
+	if !validPattern(pattern) {
+		return fmt.Errorf("%s: %q", knet.ErrInvalidTopic, pattern)
+	}
+
+	c, err := s.resolveClient(client)
+	if err != nil {
+		return err
+	}
+	return s.subscriptions.join(c, pattern)
+}
+
+// Unsubscribe removes a client's subscription to a topic pattern
+func (s *Server) Unsubscribe(client knet.Client, pattern string) error {
+	if client == nil {
+		return fmt.Errorf(knet.ErrClientNotFound)
+	}
+
+	s.subscriptions.leave(client.ID(), pattern)
+	return nil
+}
+
+// Subscriptions returns the topic patterns a client is subscribed to, sorted
+func (s *Server) Subscriptions(client knet.Client) []string {
+	if client == nil {
+		return nil
+	}
+	return s.subscriptions.roomsOf(client.ID())
+}
+
+// Publish sends a command to every client subscribed to a pattern matching topic.
+// Clients with several matching subscriptions receive the message once.
+func (s *Server) Publish(ctx context.Context, topic string, commandID uint32, payload []byte, opts ...knet.BroadcastOption) (*knet.BroadcastReport, error) {
+	if !validTopic(topic) {
+		return nil, fmt.Errorf("%s: %q", knet.ErrInvalidTopic, topic)
+	}
+
+	subscribers := s.subscriptions.matching(func(pattern string) bool {
+		return matchTopic(pattern, topic)
+	})
+
+	return s.broadcast(ctx, subscribers, commandID, payload, opts)
+}
+
+// handleSubscribe processes a CmdSubscribe request sent by a client.
+// Approved subscriptions are acknowledged by echoing CmdSubscribe with the
+// pattern; invalid or denied ones are answered with CmdSubscribeDenied.
+func (s *Server) handleSubscribe(client *Client, payload []byte) {
+	pattern := string(payload)
+
+	if !validPattern(pattern) || (s.authorizeSubscribe != nil && !s.authorizeSubscribe(client, pattern)) {
+		client.Send(context.Background(), knet.CmdSubscribeDenied, payload)
+		return
+	}
+
+	if err := s.subscriptions.join(client, pattern); err != nil {
+		return
+	}
+	client.Send(context.Background(), knet.CmdSubscribe, payload)
+}
+
+// handleUnsubscribe processes a CmdUnsubscribe request and acknowledges it
+func (s *Server) handleUnsubscribe(client *Client, payload []byte) {
+	s.subscriptions.leave(client.ID(), string(payload))
+	client.Send(context.Background(), knet.CmdUnsubscribe, payload)
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import "testing"
+
+// TestMatchTopic tests wildcard matching of topics against subscription patterns
+func TestMatchTopic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"orders.eu.created", "orders.eu.created", true},
+		{"orders.eu.created", "orders.eu.deleted", false},
+		{"orders.eu.*", "orders.eu.created", true},
+		{"orders.eu.*", "orders.eu", false},
+		{"orders.eu.*", "orders.eu.x.created", false},
+		{"orders.*.created", "orders.us.created", true},
+		{"metrics.>", "metrics.cpu", true},
+		{"metrics.>", "metrics.cpu.load", true},
+		{"metrics.>", "metrics", false},
+		{">", "anything.at.all", true},
+		{"*", "single", true},
+		{"*", "two.tokens", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("matchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+// TestValidPatternAndTopic tests pattern and topic validation
+func TestValidPatternAndTopic(t *testing.T) {
+	t.Parallel()
+
+	patterns := map[string]bool{
+		"orders.eu.*": true,
+		"metrics.>":   true,
+		">":           true,
+		"":            false,
+		"orders..eu":  false,
+		"metrics.>.x": false,
+		".orders":     false,
+	}
+	for pattern, want := range patterns {
+		if got := validPattern(pattern); got != want {
+			t.Errorf("validPattern(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+
+	topics := map[string]bool{
+		"orders.eu.created": true,
+		"orders.*":          false,
+		"metrics.>":         false,
+		"":                  false,
+		"orders.":           false,
+	}
+	for topic, want := range topics {
+		if got := validTopic(topic); got != want {
+			t.Errorf("validTopic(%q) = %v, want %v", topic, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+)
+
+// OnHandlerErrorFn is called when a handler panics, or when a fire-and-forget
+// handler returns an error (there is no reply to carry it). Errors returned by
+// request and JSON-RPC handlers are sent to the client and not reported here.
+//
+// The callback runs on the handler's goroutine, before the panic policy is applied.
+type OnHandlerErrorFn = func(err *knet.HandlerError)
+
+// PanicPolicy decides what happens to a connection after one of its handlers panics
+type PanicPolicy int
+
+const (
+	// PanicKeepConnection recovers the panic and keeps the client connected (default)
+	PanicKeepConnection PanicPolicy = iota
+	// PanicCloseConnection recovers the panic and closes the client with CloseInternalServerErr
+	PanicCloseConnection
+	// PanicRepanic reports the panic and then panics again, crashing the process
+	PanicRepanic
+)
+
+// errHandlerPanicked is returned to the dispatch path in place of a recovered panic,
+// so requests and JSON-RPC calls are answered with a generic internal error
+var errHandlerPanicked = errors.New(knet.ErrInternalError)
+
+// recoverHandler turns a recovered panic into a reported HandlerError and applies the panic policy
+func (s *Server) recoverHandler(client *Client, msg *knet.Message, p any) error {
+	s.reportHandlerError(&knet.HandlerError{
+		ClientID:  client.ID(),
+		CommandID: msg.CommandID,
+		Method:    msg.Method,
+		Err:       fmt.Errorf("panic: %v", p),
+		Panic:     p,
+		Stack:     debug.Stack(),
+	})
+
+	switch s.panicPolicy {
+	case PanicCloseConnection:
+		client.CloseWithCode(context.Background(), websocket.CloseInternalServerErr, knet.ErrInternalError)
+	case PanicRepanic:
+		panic(p)
+	}
+
+	return errHandlerPanicked
+}
+
+// reportHandlerError passes err to OnHandlerError, or logs it when no callback is set
+func (s *Server) reportHandlerError(err *knet.HandlerError) {
+	if s.onHandlerError != nil {
+		s.onHandlerError(err)
+		return
+	}
+
+	fmt.Printf("%v\n", err)
+	if err.Stack != nil {
+		fmt.Printf("%s\n", err.Stack)
+	}
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+)
+
+// handleRequestMessage handles a correlated CmdRequest frame.
+// The handler runs in its own goroutine; its result is sent as CmdResponse
+// or CmdResponseError with the request ID chosen by the client.
+func (s *Server) handleRequestMessage(client *Client, frame *knet.Message) {
+	commandID, requestID, payload, err := protocol.DecodeRequest(frame.Payload)
+	if err != nil {
+		// Without a request ID there is nothing to reply to
+		client.CloseWithCode(context.Background(), websocket.CloseProtocolError, knet.ErrInvalidMessageFormat)
+		return
+	}
+
+	entry, ok := s.requestHandlers.Load(commandID)
+	if !ok {
+		s.sendRequestError(client, requestID, &knet.RequestError{
+			Code:    knet.RequestErrUnknownCommand,
+			Message: fmt.Sprintf("%s: 0x%08X", knet.ErrUnknownCommand, commandID),
+		})
+		return
+	}
+
+	msg := &knet.Message{
+		Kind:       knet.KindRequest,
+		CommandID:  commandID,
+		Payload:    payload,
+		Sequence:   frame.Sequence,
+		ReceivedAt: frame.ReceivedAt,
+	}
+
+	s.schedule(client, func() {
+		result, err := s.invoke(client, msg, entry.(*handlerEntry))
+		if permErr, ok := s.permissionDenied(client, msg, err); ok {
+			err = &knet.RequestError{Code: knet.RequestErrPermissionDenied, Message: permErr.Error()}
+		}
+		if err != nil {
+			s.sendRequestError(client, requestID, err)
+			return
+		}
+
+		reply, ok := result.([]byte)
+		if !ok && result != nil {
+			// Middleware replaced the reply with something that is not a payload
+			s.sendRequestError(client, requestID, &knet.RequestError{Code: knet.RequestErrInternal, Message: knet.ErrInternalError})
+			return
+		}
+		client.Send(context.Background(), knet.CmdResponse, protocol.EncodeResponse(requestID, reply))
+	})
+}
+
+// sendRequestError sends a CmdResponseError frame for requestID.
+// A *knet.RequestError keeps its code; other errors become RequestErrInternal.
+func (s *Server) sendRequestError(client *Client, requestID uint32, err error) {
+	reqErr := &knet.RequestError{Code: knet.RequestErrInternal, Message: err.Error()}
+	errors.As(err, &reqErr)
+
+	if err := client.Send(context.Background(), knet.CmdResponseError, protocol.EncodeError(requestID, reqErr.Code, reqErr.Message)); err != nil {
+		// Log error - client may have disconnected
+		fmt.Printf("Failed to send request error response to client %s: %v\n", client.ID(), err)
+	}
+}
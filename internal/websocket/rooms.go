@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/luciancaetano/knet"
+)
+
+// groupRegistry tracks which clients belong to which named groups.
+// It backs both rooms and topic subscriptions (keyed by pattern).
+// All methods are safe for concurrent use.
+type groupRegistry struct {
+	mu       sync.RWMutex
+	rooms    map[string]map[string]*Client  // group -> client ID -> client
+	byClient map[string]map[string]struct{} // client ID -> groups
+}
+
+func newGroupRegistry() *groupRegistry {
+	return &groupRegistry{
+		rooms:    make(map[string]map[string]*Client),
+		byClient: make(map[string]map[string]struct{}),
+	}
+}
+
+// join adds the client to the room. Closed clients are rejected so a join
+// racing with disconnect cannot leave a stale membership behind.
+func (r *groupRegistry) join(client *Client, room string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !client.IsAlive() {
+		return fmt.Errorf(knet.ErrConnectionClosed)
+	}
+
+	members, ok := r.rooms[room]
+	if !ok {
+		members = make(map[string]*Client)
+		r.rooms[room] = members
+	}
+	members[client.ID()] = client
+
+	joined, ok := r.byClient[client.ID()]
+	if !ok {
+		joined = make(map[string]struct{})
+		r.byClient[client.ID()] = joined
+	}
+	joined[room] = struct{}{}
+	return nil
+}
+
+// leave removes the client from the room, deleting the room once it is empty
+func (r *groupRegistry) leave(clientID, room string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(clientID, room)
+}
+
+// leaveAll removes the client from every room it joined
+func (r *groupRegistry) leaveAll(clientID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for room := range r.byClient[clientID] {
+		r.removeLocked(clientID, room)
+	}
+}
+
+func (r *groupRegistry) removeLocked(clientID, room string) {
+	if members, ok := r.rooms[room]; ok {
+		delete(members, clientID)
+		if len(members) == 0 {
+			delete(r.rooms, room)
+		}
+	}
+
+	if joined, ok := r.byClient[clientID]; ok {
+		delete(joined, room)
+		if len(joined) == 0 {
+			delete(r.byClient, clientID)
+		}
+	}
+}
+
+// members returns a snapshot of the clients in the room
+func (r *groupRegistry) members(room string) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := make([]*Client, 0, len(r.rooms[room]))
+	for _, client := range r.rooms[room] {
+		members = append(members, client)
+	}
+	return members
+}
+
+// roomsOf returns the sorted names of the rooms the client belongs to
+func (r *groupRegistry) roomsOf(clientID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rooms := make([]string, 0, len(r.byClient[clientID]))
+	for room := range r.byClient[clientID] {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// matching returns the distinct clients of every group whose name satisfies match
+func (r *groupRegistry) matching(match func(name string) bool) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	var clients []*Client
+	for name, members := range r.rooms {
+		if !match(name) {
+			continue
+		}
+		for id, client := range members {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// list returns the sorted names of all non-empty rooms
+func (r *groupRegistry) list() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rooms := make([]string, 0, len(r.rooms))
+	for room := range r.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// resolveClient maps a knet.Client to the connection registered on this server
+func (s *Server) resolveClient(client knet.Client) (*Client, error) {
+	if client == nil {
+		return nil, fmt.Errorf(knet.ErrClientNotFound)
+	}
+	c, ok := s.GetClient(client.ID())
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", knet.ErrClientNotFound, client.ID())
+	}
+	return c, nil
+}
+
+// Join adds a connected client to a room. Joining a room twice is a no-op.
+func (s *Server) Join(client knet.Client, room string) error {
+	if room == "" {
+		return fmt.Errorf(knet.ErrInvalidRoom)
+	}
+
+	c, err := s.resolveClient(client)
+	if err != nil {
+		return err
+	}
+	return s.rooms.join(c, room)
+}
+
+// Leave removes a client from a room. Leaving a room the client is not in is a no-op.
+func (s *Server) Leave(client knet.Client, room string) error {
+	if room == "" {
+		return fmt.Errorf(knet.ErrInvalidRoom)
+	}
+	if client == nil {
+		return fmt.Errorf(knet.ErrClientNotFound)
+	}
+
+	s.rooms.leave(client.ID(), room)
+	return nil
+}
+
+// BroadcastToRoom sends a command to every member of a room except the excluded client IDs.
+// The report always includes per-client errors.
+func (s *Server) BroadcastToRoom(ctx context.Context, room string, commandID uint32, payload []byte, exclude ...string) (*knet.BroadcastReport, error) {
+	include := excluding(exclude)
+
+	var targets []*Client
+	for _, client := range s.rooms.members(room) {
+		if include(client) {
+			targets = append(targets, client)
+		}
+	}
+
+	return s.broadcast(ctx, targets, commandID, payload, []knet.BroadcastOption{knet.WithClientErrors()})
+}
+
+// RoomMembers returns the clients currently in a room
+func (s *Server) RoomMembers(room string) []knet.Client {
+	members := s.rooms.members(room)
+	clients := make([]knet.Client, len(members))
+	for i, member := range members {
+		clients[i] = member
+	}
+	return clients
+}
+
+// ClientRooms returns the rooms a client belongs to, sorted by name
+func (s *Server) ClientRooms(client knet.Client) []string {
+	if client == nil {
+		return nil
+	}
+	return s.rooms.roomsOf(client.ID())
+}
+
+// Rooms returns the names of all rooms that currently have members, sorted by name
+func (s *Server) Rooms() []string {
+	return s.rooms.list()
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// newTestClient creates a client without a connection for registry tests
+func newTestClient(id string) *Client {
+	return &Client{id: id}
+}
+
+// TestGroupRegistryJoinLeave tests basic membership bookkeeping
+func TestGroupRegistryJoinLeave(t *testing.T) {
+	t.Parallel()
+
+	r := newGroupRegistry()
+	alice, bob := newTestClient("alice"), newTestClient("bob")
+
+	r.join(alice, "lobby")
+	r.join(alice, "game")
+	r.join(bob, "lobby")
+
+	if got := r.list(); !reflect.DeepEqual(got, []string{"game", "lobby"}) {
+		t.Errorf("list() = %v, want [game lobby]", got)
+	}
+
+	if got := len(r.members("lobby")); got != 2 {
+		t.Errorf("len(members(lobby)) = %d, want 2", got)
+	}
+
+	r.leave("alice", "game")
+	if got := r.list(); !reflect.DeepEqual(got, []string{"lobby"}) {
+		t.Errorf("empty room should be removed, list() = %v", got)
+	}
+
+	r.leaveAll("bob")
+	if got := r.roomsOf("bob"); len(got) != 0 {
+		t.Errorf("roomsOf(bob) = %v, want none", got)
+	}
+	if got := r.roomsOf("alice"); !reflect.DeepEqual(got, []string{"lobby"}) {
+		t.Errorf("roomsOf(alice) = %v, want [lobby]", got)
+	}
+}
+
+// TestGroupRegistryRejectsClosedClient tests that closed clients cannot join
+func TestGroupRegistryRejectsClosedClient(t *testing.T) {
+	t.Parallel()
+
+	r := newGroupRegistry()
+	client := newTestClient("closed")
+	client.closed = true
+
+	if err := r.join(client, "lobby"); err == nil {
+		t.Error("expected join of a closed client to fail")
+	}
+
+	if got := r.list(); len(got) != 0 {
+		t.Errorf("list() = %v, want no rooms", got)
+	}
+}
+
+// TestGroupRegistryConcurrency tests concurrent joins, leaves and reads
+func TestGroupRegistryConcurrency(t *testing.T) {
+	t.Parallel()
+
+	r := newGroupRegistry()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		client := newTestClient(fmt.Sprintf("client-%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				room := fmt.Sprintf("room-%d", j%5)
+				r.join(client, room)
+				_ = r.members(room)
+				if j%2 == 0 {
+					r.leave(client.ID(), room)
+				}
+			}
+			r.leaveAll(client.ID())
+		}()
+	}
+
+	wg.Wait()
+
+	if got := r.list(); len(got) != 0 {
+		t.Errorf("list() = %v, want no rooms after every client left", got)
+	}
+}
@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/schema"
+)
+
+// SchemaConfig describes the server in the generated OpenRPC document and
+// command catalog, and optionally serves them over HTTP.
+type SchemaConfig struct {
+	// Path serves the documents at Path+"/openrpc.json" and Path+"/commands.json"
+	// from Start and Serve, next to the WebSocket endpoint. Empty disables it.
+	Path string
+	// Title and Version identify the API. They default to "knet" and "1.0.0".
+	Title       string
+	Version     string
+	Description string
+}
+
+// OpenRPC generates the OpenRPC document of the registered JSON-RPC methods
+func (s *Server) OpenRPC() *knet.OpenRPCDocument {
+	info := knet.OpenRPCInfo{Title: "knet", Version: "1.0.0"}
+	if s.schema != nil {
+		if s.schema.Title != "" {
+			info.Title = s.schema.Title
+		}
+		if s.schema.Version != "" {
+			info.Version = s.schema.Version
+		}
+		info.Description = s.schema.Description
+	}
+
+	gen := schema.NewGenerator("#/components/schemas/")
+	methods := []knet.OpenRPCMethod{}
+	s.jsonRPCHandlers.Range(func(key, value interface{}) bool {
+		methods = append(methods, describeMethod(gen, key.(string), value.(*handlerEntry).options))
+		return true
+	})
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+
+	doc := &knet.OpenRPCDocument{OpenRPC: knet.OpenRPCVersion, Info: info, Methods: methods}
+	if definitions := gen.Definitions(); definitions != nil {
+		doc.Components = &knet.OpenRPCComponents{Schemas: definitions}
+	}
+	return doc
+}
+
+// CommandCatalog generates the catalog of the registered binary commands
+func (s *Server) CommandCatalog() *knet.CommandCatalog {
+	gen := schema.NewGenerator("#/schemas/")
+	commands := []knet.CommandDescriptor{}
+	describe := func(kind string) func(key, value interface{}) bool {
+		return func(key, value interface{}) bool {
+			options := value.(*handlerEntry).options
+			commands = append(commands, knet.CommandDescriptor{
+				ID:          key.(uint32),
+				Kind:        kind,
+				Name:        options.Name,
+				Description: options.Description,
+				Request:     payloadSchema(gen, options.RequestType),
+				Response:    payloadSchema(gen, options.ResponseType),
+			})
+			return true
+		}
+	}
+	s.handlers.Range(describe("command"))
+	s.requestHandlers.Range(describe("request"))
+	sort.Slice(commands, func(i, j int) bool {
+		if commands[i].ID != commands[j].ID {
+			return commands[i].ID < commands[j].ID
+		}
+		return commands[i].Kind < commands[j].Kind
+	})
+
+	return &knet.CommandCatalog{Commands: commands, Schemas: gen.Definitions()}
+}
+
+// SchemaHandler serves the OpenRPC document at ".../openrpc.json" and the
+// command catalog at ".../commands.json"
+func (s *Server) SchemaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var doc any
+		switch path.Base(r.URL.Path) {
+		case "openrpc.json":
+			doc = s.OpenRPC()
+		case "commands.json":
+			doc = s.CommandCatalog()
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
+
+// describeMethod builds the OpenRPC description of a JSON-RPC method.
+// Struct params are described by name, array params by position, and any
+// other params type as a single "params" entry.
+func describeMethod(gen *schema.Generator, name string, options knet.HandlerOptions) knet.OpenRPCMethod {
+	method := knet.OpenRPCMethod{
+		Name:        name,
+		Summary:     options.Name,
+		Description: options.Description,
+		Params:      []knet.ContentDescriptor{},
+		Result:      &knet.ContentDescriptor{Name: "result", Schema: knet.Schema{}},
+	}
+	if options.ResponseType != nil {
+		method.Result.Schema = gen.Schema(options.ResponseType)
+	}
+
+	params := options.RequestType
+	if params == nil {
+		return method
+	}
+	for params.Kind() == reflect.Pointer {
+		params = params.Elem()
+	}
+
+	switch params.Kind() {
+	case reflect.Struct:
+		method.ParamStructure = "by-name"
+		for _, f := range gen.Fields(params) {
+			method.Params = append(method.Params, knet.ContentDescriptor{Name: f.Name, Required: f.Required, Schema: f.Schema})
+		}
+	case reflect.Array:
+		method.ParamStructure = "by-position"
+		item := gen.Schema(params.Elem())
+		for i := 0; i < params.Len(); i++ {
+			method.Params = append(method.Params, knet.ContentDescriptor{Name: "arg" + strconv.Itoa(i), Required: true, Schema: item})
+		}
+	default:
+		method.Params = append(method.Params, knet.ContentDescriptor{Name: "params", Required: true, Schema: gen.Schema(params)})
+	}
+	return method
+}
+
+// payloadSchema returns the schema of a command payload type, nil when the
+// type is unknown or struct{}
+func payloadSchema(gen *schema.Generator, t reflect.Type) knet.Schema {
+	if t == nil || (t.Kind() == reflect.Struct && t.NumField() == 0) {
+		return nil
+	}
+	return gen.Schema(t)
+}
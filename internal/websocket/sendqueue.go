@@ -0,0 +1,202 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+)
+
+// SendQueuePolicy decides what happens to an outgoing message when the
+// client's send queue is full
+type SendQueuePolicy int
+
+const (
+	// SendQueueBlock makes Send wait until the queue has room or its context ends (default)
+	SendQueueBlock SendQueuePolicy = iota
+	// SendQueueDropNewest discards the message being sent; Send returns an error
+	SendQueueDropNewest
+	// SendQueueDropOldest discards the oldest queued message to make room
+	SendQueueDropOldest
+	// SendQueueCoalesce discards queued messages with the same command ID, so only
+	// the latest state update is written. When there is none, the oldest queued
+	// message is discarded instead.
+	SendQueueCoalesce
+	// SendQueueDisconnect closes the client with CloseTryAgainLater
+	SendQueueDisconnect
+)
+
+// DefaultSendQueueSize is the send queue size used when SendQueueConfig.Size is zero
+const DefaultSendQueueSize = 256
+
+// SendQueueConfig defines the per-client queue of messages waiting to be written
+type SendQueueConfig struct {
+	// Size is the number of messages that may wait per client. Defaults to DefaultSendQueueSize.
+	Size int
+	// Policy applies when the queue is full
+	Policy SendQueuePolicy
+}
+
+// normalize fills in the default size
+func (cfg SendQueueConfig) normalize() SendQueueConfig {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSendQueueSize
+	}
+	return cfg
+}
+
+// OnSlowConsumerFn is called when a full send queue makes the server drop a
+// message or disconnect the client. commandID is the command of the dropped
+// message, or of the message that could not be queued when the client is
+// disconnected.
+//
+// The callback runs on the goroutine that called Send. Avoid blocking in it.
+type OnSlowConsumerFn = func(client knet.Client, policy SendQueuePolicy, commandID uint32)
+
+// errSendQueueFull is returned by Send when the message is dropped or the client
+// is disconnected because its send queue is full
+var errSendQueueFull = errors.New(knet.ErrSendQueueFull)
+
+// errWouldBlock is returned by a non-waiting enqueue when SendQueueBlock
+// would have made the caller wait
+var errWouldBlock = errors.New(knet.ErrSendQueueFull)
+
+// outboundMessage is an encoded frame waiting to be written.
+// Broadcasts share one prepared message between clients instead of data.
+type outboundMessage struct {
+	command  uint32
+	data     []byte
+	prepared *websocket.PreparedMessage
+}
+
+// sendQueue is a bounded FIFO of outbound messages that, unlike a channel,
+// lets older messages be evicted when it is full
+type sendQueue struct {
+	mu      sync.Mutex
+	items   []outboundMessage
+	size    int
+	pending int // queued messages plus the one being written
+	closed  bool
+
+	ready chan struct{} // signalled when a message is queued
+	space chan struct{} // signalled when a message leaves the queue
+}
+
+func newSendQueue(size int) *sendQueue {
+	return &sendQueue{
+		items: make([]outboundMessage, 0, size),
+		size:  size,
+		ready: make(chan struct{}, 1),
+		space: make(chan struct{}, 1),
+	}
+}
+
+// push queues msg. When the queue is full, SendQueueDropOldest and
+// SendQueueCoalesce evict older messages and return them; other policies
+// leave the queue untouched and report queued=false.
+func (q *sendQueue) push(msg outboundMessage, policy SendQueuePolicy) (queued bool, dropped []outboundMessage, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false, nil, fmt.Errorf(knet.ErrConnectionClosed)
+	}
+
+	if len(q.items) >= q.size {
+		switch policy {
+		case SendQueueCoalesce:
+			dropped = q.removeLocked(func(m outboundMessage) bool { return m.command == msg.command })
+			if len(dropped) == 0 {
+				dropped = q.removeOldestLocked()
+			}
+		case SendQueueDropOldest:
+			dropped = q.removeOldestLocked()
+		default:
+			return false, nil, nil
+		}
+	}
+
+	q.items = append(q.items, msg)
+	q.pending++
+	signal(q.ready)
+	if len(q.items) < q.size {
+		// Pass the wake-up on to the next blocked sender
+		signal(q.space)
+	}
+	return true, dropped, nil
+}
+
+// removeLocked removes the queued messages matching drop, preserving order
+func (q *sendQueue) removeLocked(drop func(outboundMessage) bool) []outboundMessage {
+	var dropped []outboundMessage
+	kept := q.items[:0]
+	for _, m := range q.items {
+		if drop(m) {
+			dropped = append(dropped, m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	q.items = kept
+	q.pending -= len(dropped)
+	return dropped
+}
+
+// removeOldestLocked removes the head of the queue
+func (q *sendQueue) removeOldestLocked() []outboundMessage {
+	oldest := q.items[0]
+	q.items = q.items[1:]
+	q.pending--
+	return []outboundMessage{oldest}
+}
+
+// pop takes the oldest message for writing. It stays pending until done is called.
+func (q *sendQueue) pop() (outboundMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || len(q.items) == 0 {
+		return outboundMessage{}, false
+	}
+	msg := q.items[0]
+	q.items = q.items[1:]
+	signal(q.space)
+	return msg, true
+}
+
+// done marks a popped message as written (or failed)
+func (q *sendQueue) done() {
+	q.mu.Lock()
+	if !q.closed {
+		q.pending--
+	}
+	q.mu.Unlock()
+}
+
+// empty reports whether every queued message has been written
+func (q *sendQueue) empty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending == 0
+}
+
+// close discards queued messages and rejects new ones
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.items = nil
+	q.pending = 0
+}
+
+// signal wakes one waiter of ch without blocking
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"testing"
+)
+
+// fillQueue pushes one message per command and fails the test if any is rejected
+func fillQueue(t *testing.T, q *sendQueue, commands ...uint32) {
+	t.Helper()
+
+	for _, command := range commands {
+		if queued, _, err := q.push(outboundMessage{command: command}, SendQueueBlock); !queued || err != nil {
+			t.Fatalf("push(%d) = %v, %v, want queued", command, queued, err)
+		}
+	}
+}
+
+// queuedCommands pops every queued message and returns their commands
+func queuedCommands(q *sendQueue) []uint32 {
+	var commands []uint32
+	for {
+		msg, ok := q.pop()
+		if !ok {
+			return commands
+		}
+		q.done()
+		commands = append(commands, msg.command)
+	}
+}
+
+func equalCommands(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSendQueuePolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		policy      SendQueuePolicy
+		queued      []uint32
+		push        uint32
+		wantQueued  bool
+		wantDropped []uint32
+		wantOrder   []uint32
+	}{
+		{"block", SendQueueBlock, []uint32{1, 2, 3}, 4, false, nil, []uint32{1, 2, 3}},
+		{"drop newest", SendQueueDropNewest, []uint32{1, 2, 3}, 4, false, nil, []uint32{1, 2, 3}},
+		{"disconnect", SendQueueDisconnect, []uint32{1, 2, 3}, 4, false, nil, []uint32{1, 2, 3}},
+		{"drop oldest", SendQueueDropOldest, []uint32{1, 2, 3}, 4, true, []uint32{1}, []uint32{2, 3, 4}},
+		{"coalesce same command", SendQueueCoalesce, []uint32{1, 2, 1}, 1, true, []uint32{1, 1}, []uint32{2, 1}},
+		{"coalesce falls back to oldest", SendQueueCoalesce, []uint32{1, 2, 3}, 4, true, []uint32{1}, []uint32{2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := newSendQueue(len(tt.queued))
+			fillQueue(t, q, tt.queued...)
+
+			queued, dropped, err := q.push(outboundMessage{command: tt.push}, tt.policy)
+			if err != nil {
+				t.Fatalf("push() error = %v", err)
+			}
+			if queued != tt.wantQueued {
+				t.Errorf("queued = %v, want %v", queued, tt.wantQueued)
+			}
+
+			var droppedCommands []uint32
+			for _, msg := range dropped {
+				droppedCommands = append(droppedCommands, msg.command)
+			}
+			if !equalCommands(droppedCommands, tt.wantDropped) {
+				t.Errorf("dropped = %v, want %v", droppedCommands, tt.wantDropped)
+			}
+
+			if got := queuedCommands(q); !equalCommands(got, tt.wantOrder) {
+				t.Errorf("queue = %v, want %v", got, tt.wantOrder)
+			}
+			if !q.empty() {
+				t.Error("queue should be empty after every message was written")
+			}
+		})
+	}
+}
+
+func TestSendQueueClosed(t *testing.T) {
+	t.Parallel()
+
+	q := newSendQueue(2)
+	fillQueue(t, q, 1)
+
+	msg, _ := q.pop()
+	q.close()
+	q.done()
+
+	if !q.empty() {
+		t.Error("closed queue should be empty")
+	}
+	if _, _, err := q.push(msg, SendQueueBlock); err == nil {
+		t.Error("push() on a closed queue should fail")
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("pop() on a closed queue should return nothing")
+	}
+}
@@ -147,7 +147,7 @@ func TestNewServer(t *testing.T) {
 			t.Parallel()
 
 			server := New(&ServerConfig{
-				Addr:               ":8080",
+				Addr:               tt.addr,
 				RateLimitConfig:    tt.rateLimitConfig,
 				CheckOrigin:        tt.checkOrigin,
 				OnConnect:          nil,
@@ -179,7 +179,7 @@ func TestServerInitialState(t *testing.T) {
 	t.Parallel()
 
 	server := New(&ServerConfig{
-		Addr:               ":8080",
+		Addr:               ":8084",
 		RateLimitConfig:    DefaultRateLimitConfig(),
 		CheckOrigin:        nil,
 		OnConnect:          nil,
@@ -194,6 +194,10 @@ func TestServerInitialState(t *testing.T) {
 		t.Errorf("server.addr = %v, want :8084", server.addr)
 	}
 
+	if server.path != DefaultPath {
+		t.Errorf("server.path = %v, want %v", server.path, DefaultPath)
+	}
+
 	if server.upgrader.ReadBufferSize != 1024 {
 		t.Errorf("upgrader.ReadBufferSize = %v, want 1024", server.upgrader.ReadBufferSize)
 	}
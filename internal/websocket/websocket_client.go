@@ -2,8 +2,11 @@ package websocket
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,14 +24,45 @@ type Client struct {
 	remoteAddr  string
 	ctx         context.Context
 	cancel      context.CancelFunc
-	sendCh      chan []byte
+	outbound    *sendQueue
 	mu          sync.RWMutex
 	closed      bool
 	rateLimiter *rate.Limiter // Rate limiter for incoming messages
+
+	// Snapshot of the upgrade request, nil for clients not created by the server
+	handshake *knet.Handshake
+
+	// Pending server-initiated calls, keyed by kind and request ID (nil once disconnected)
+	callsMu    sync.Mutex
+	calls      map[callKey]chan callResult
+	nextCallID atomic.Uint32
+
+	// Ordered handler queue, only used in DispatchPerClient mode
+	queue chan func()
+
+	// Sequence number of the last message read from the client
+	sequence atomic.Uint64
+
+	// Full send queue handling
+	sendPolicy     SendQueuePolicy
+	onSlowConsumer OnSlowConsumerFn
+
+	// Per-connection attributes, cleared on disconnect, and the
+	// authenticated identity (guarded by attrsMu)
+	attrsMu   sync.RWMutex
+	attrs     map[string]any
+	principal any
+
+	// In-band authentication state; authCtx bounds the validator while the
+	// client is unauthenticated
+	authenticated atomic.Bool
+	authCtx       context.Context
 }
 
-// NewClient creates a new WebSocket client with rate limiting
-func NewClient(conn *websocket.Conn, remoteAddr string, rateLimitConfig *RateLimitConfig) *Client {
+// NewClient creates a new WebSocket client with rate limiting.
+// When sendQueueConfig is nil, the send queue holds DefaultSendQueueSize
+// messages and Send blocks while it is full.
+func NewClient(conn *websocket.Conn, remoteAddr string, rateLimitConfig *RateLimitConfig, sendQueueConfig *SendQueueConfig, onSlowConsumer OnSlowConsumerFn) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var limiter *rate.Limiter
@@ -36,15 +70,25 @@ func NewClient(conn *websocket.Conn, remoteAddr string, rateLimitConfig *RateLim
 		limiter = rate.NewLimiter(rateLimitConfig.MessagesPerSecond, rateLimitConfig.Burst)
 	}
 
+	var sendQueue SendQueueConfig
+	if sendQueueConfig != nil {
+		sendQueue = *sendQueueConfig
+	}
+	sendQueue = sendQueue.normalize()
+
 	client := &Client{
 		id:          uuid.New().String(),
 		conn:        conn,
 		remoteAddr:  remoteAddr,
 		ctx:         ctx,
 		cancel:      cancel,
-		sendCh:      make(chan []byte, 256),
+		outbound:    newSendQueue(sendQueue.Size),
 		closed:      false,
 		rateLimiter: limiter,
+		calls:       make(map[callKey]chan callResult),
+
+		sendPolicy:     sendQueue.Policy,
+		onSlowConsumer: onSlowConsumer,
 	}
 
 	// Start the write pump
@@ -63,36 +107,94 @@ func (c *Client) RemoteAddr() string {
 	return c.remoteAddr
 }
 
+// PeerCertificate returns the verified client certificate, or nil when the
+// connection did not present one (or it was not verified)
+func (c *Client) PeerCertificate() *x509.Certificate {
+	if c.handshake == nil {
+		return nil
+	}
+	state := c.handshake.TLS()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// Handshake returns the snapshot of the HTTP upgrade request
+func (c *Client) Handshake() *knet.Handshake {
+	if c.handshake == nil {
+		return knet.NewHandshake(&http.Request{}, "")
+	}
+	return c.handshake
+}
+
 // Context returns the client's lifecycle context
 func (c *Client) Context() context.Context {
 	return c.ctx
 }
 
-// Send encodes and sends a message with the given command ID and payload
+// Send encodes and queues a message with the given command ID and payload.
+// When the send queue is full, the client's SendQueuePolicy applies.
 func (c *Client) Send(ctx context.Context, command uint32, payload []byte) error {
-	// Encode the message using protocol first (before acquiring lock)
+	// Encode the message using protocol first
 	data, err := protocol.Encode(command, payload)
 	if err != nil {
 		return fmt.Errorf("%s: %w", knet.ErrFailedToEncode, err)
 	}
 
-	c.mu.RLock()
-	if c.closed {
-		c.mu.RUnlock()
+	return c.enqueue(ctx, outboundMessage{command: command, data: data}, true)
+}
+
+// enqueue queues msg, applying the send queue policy when the queue is full.
+// With wait=false, a full queue under SendQueueBlock returns errWouldBlock
+// instead of waiting for room.
+func (c *Client) enqueue(ctx context.Context, msg outboundMessage, wait bool) error {
+	if !c.IsAlive() {
 		return fmt.Errorf(knet.ErrConnectionClosed)
 	}
 
-	// Keep the lock while sending to prevent race with Close()
-	select {
-	case c.sendCh <- data:
-		c.mu.RUnlock()
-		return nil
-	case <-ctx.Done():
-		c.mu.RUnlock()
-		return ctx.Err()
-	case <-c.ctx.Done():
-		c.mu.RUnlock()
-		return fmt.Errorf(knet.ErrContextCancelled)
+	for {
+		queued, dropped, err := c.outbound.push(msg, c.sendPolicy)
+		if err != nil {
+			return err
+		}
+		for _, m := range dropped {
+			c.slowConsumer(m.command)
+		}
+		if queued {
+			return nil
+		}
+
+		switch c.sendPolicy {
+		case SendQueueDropNewest:
+			c.slowConsumer(msg.command)
+			return errSendQueueFull
+		case SendQueueDisconnect:
+			c.slowConsumer(msg.command)
+			// The write pump is likely stuck on this client, so do not wait for the close frame
+			c.closeAsync(websocket.CloseTryAgainLater, knet.ErrSendQueueFull)
+			return errSendQueueFull
+		}
+
+		if !wait {
+			return errWouldBlock
+		}
+
+		// SendQueueBlock: wait for the write pump to make room
+		select {
+		case <-c.outbound.space:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return fmt.Errorf(knet.ErrContextCancelled)
+		}
+	}
+}
+
+// slowConsumer reports a message dropped (or a disconnect) because the send queue is full
+func (c *Client) slowConsumer(command uint32) {
+	if c.onSlowConsumer != nil {
+		c.onSlowConsumer(c, c.sendPolicy, command)
 	}
 }
 
@@ -103,22 +205,41 @@ func (c *Client) Close(ctx context.Context) error {
 
 // CloseWithCode closes the connection with a close code and optional reason
 func (c *Client) CloseWithCode(ctx context.Context, code int, reason string) error {
+	if !c.markClosed() {
+		return nil
+	}
+	return c.writeClose(code, reason)
+}
+
+// closeAsync marks the client closed at once and sends the close frame in the
+// background, for callers that must not wait up to a second on a stuck writer
+func (c *Client) closeAsync(code int, reason string) {
+	if c.markClosed() {
+		go c.writeClose(code, reason)
+	}
+}
+
+// markClosed cancels the client and discards its send queue.
+// It returns false when the client was already closed.
+func (c *Client) markClosed() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.closed {
-		return nil
+		return false
 	}
-
 	c.closed = true
 	c.cancel()
+	c.outbound.close()
+	return true
+}
 
-	// Send close message
+// writeClose sends the close message and closes the connection
+func (c *Client) writeClose(code int, reason string) error {
 	message := websocket.FormatCloseMessage(code, reason)
 	deadline := time.Now().Add(time.Second)
 	c.conn.WriteControl(websocket.CloseMessage, message, deadline)
 
-	close(c.sendCh)
 	return c.conn.Close()
 }
 
@@ -139,7 +260,7 @@ func (c *Client) CheckRateLimit(ctx context.Context) bool {
 	return c.rateLimiter.Allow()
 }
 
-// writePump pumps messages from the send channel to the websocket connection
+// writePump pumps messages from the send queue to the websocket connection
 func (c *Client) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
 	defer func() {
@@ -149,16 +270,24 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.sendCh:
-			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				// Channel closed
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+		case <-c.outbound.ready:
+			for {
+				message, ok := c.outbound.pop()
+				if !ok {
+					break
+				}
 
-			if err := c.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
-				return
+				c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				var err error
+				if message.prepared != nil {
+					err = c.conn.WritePreparedMessage(message.prepared)
+				} else {
+					err = c.conn.WriteMessage(websocket.BinaryMessage, message.data)
+				}
+				c.outbound.done()
+				if err != nil {
+					return
+				}
 			}
 
 		case <-ticker.C:
@@ -174,6 +303,11 @@ func (c *Client) writePump() {
 	}
 }
 
+// flushed reports whether every message accepted by Send has been written
+func (c *Client) flushed() bool {
+	return c.outbound.empty()
+}
+
 // SetPongHandler sets the handler for pong messages
 func (c *Client) SetPongHandler(handler func(appData string) error) {
 	c.conn.SetPongHandler(handler)
@@ -2,10 +2,16 @@ package websocket
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -42,12 +48,90 @@ type OnConnectFn = func(client knet.Client)
 // application-specific notification when a client connection ends.
 type OnClientDisconnectFn = func(client knet.Client, voluntary bool)
 
+// AuthenticateFn authenticates an HTTP request before it is upgraded to a WebSocket connection.
+// The returned principal is attached to the client (see knet.Client.Principal).
+// Return a *knet.AuthError to choose the rejection status and body; other errors
+// reject the request with 401 Unauthorized.
+//
+// Note: This function is called synchronously in the HTTP handler, after CheckOrigin,
+// so cross-origin requests are rejected before reaching it.
+type AuthenticateFn = func(r *http.Request) (principal any, err error)
+
+// DefaultPath is the HTTP path the WebSocket endpoint is mounted on by Start and Serve
+// when ServerConfig.Path is empty.
+const DefaultPath = "/ws"
+
 type ServerConfig struct {
 	Addr               string
 	RateLimitConfig    *RateLimitConfig
 	CheckOrigin        CheckOriginFn
 	OnConnect          OnConnectFn
 	OnClientDisconnect OnClientDisconnectFn
+
+	// Authenticate runs before the upgrade and may reject the request with an
+	// HTTP error. When nil, every request is upgraded.
+	Authenticate AuthenticateFn
+	// InBandAuth requires clients to authenticate with their first message.
+	// When nil, connections are usable as soon as they open.
+	InBandAuth *InBandAuthConfig
+
+	// Path is the HTTP path used by Start and Serve. Defaults to DefaultPath.
+	// It is ignored when the server is mounted through Handler.
+	Path string
+
+	// Schema describes the API in the generated OpenRPC document and command
+	// catalog, and may serve them next to the WebSocket endpoint.
+	Schema *SchemaConfig
+
+	// TLSConfig enables TLS (wss://) for Start and Serve. Set ClientAuth and
+	// ClientCAs to require and verify client certificates (mutual TLS).
+	TLSConfig *tls.Config
+	// CertFile and KeyFile load the server certificate from PEM files.
+	// They may be combined with TLSConfig or used on their own.
+	CertFile string
+	KeyFile  string
+
+	// Subprotocols lists the WebSocket subprotocols the server supports, in
+	// order of preference. The negotiated one is available through
+	// knet.Client.Handshake.
+	Subprotocols []string
+
+	// AuthorizeSubscribe approves or denies topic subscriptions requested by
+	// clients. When nil, every valid subscription is approved.
+	AuthorizeSubscribe AuthorizeSubscribeFn
+
+	// OnHandlerError receives handler panics and fire-and-forget handler errors.
+	// When nil, they are logged to stdout.
+	OnHandlerError OnHandlerErrorFn
+	// PanicPolicy decides what happens to a connection whose handler panicked.
+	// Defaults to PanicKeepConnection.
+	PanicPolicy PanicPolicy
+
+	// OnPermissionDenied audits messages denied for lack of a permission.
+	// When nil, denials are logged to stdout.
+	OnPermissionDenied OnPermissionDeniedFn
+
+	// JSONRPCErrorMapper translates errors returned by JSON-RPC handlers that
+	// are not a *knet.RPCError. When nil, or when it returns nil, they are
+	// answered with a generic Internal error and passed to OnHandlerError.
+	JSONRPCErrorMapper JSONRPCErrorMapperFn
+
+	// Dispatch selects how handlers are scheduled. When nil, every handler
+	// runs in its own goroutine (DispatchUnbounded).
+	Dispatch *DispatchConfig
+
+	// SendQueue sizes each client's outgoing queue and decides what happens
+	// when it is full. When nil, DefaultSendQueueSize messages may wait and
+	// Send blocks on a full queue (SendQueueBlock).
+	SendQueue *SendQueueConfig
+	// OnSlowConsumer is called for every message dropped, and every client
+	// disconnected, because of a full send queue
+	OnSlowConsumer OnSlowConsumerFn
+}
+
+// tlsEnabled reports whether the configuration requests TLS
+func (cfg *ServerConfig) tlsEnabled() bool {
+	return cfg.TLSConfig != nil || (cfg.CertFile != "" && cfg.KeyFile != "")
 }
 
 // RateLimitConfig defines rate limiting configuration for clients
@@ -80,12 +164,53 @@ func NoRateLimit() *RateLimitConfig {
 // Server implements the WebsocketServer interface
 type Server struct {
 	addr     string
+	path     string
+	schema   *SchemaConfig
 	server   *http.Server
+	tls      bool
+	tlsCfg   *tls.Config
+	certFile string
+	keyFile  string
 	clients  sync.Map // map[string]*Client
-	handlers sync.Map // map[uint32]func(client knet.Client, payload []byte)
+	handlers sync.Map // map[uint32]*handlerEntry
+
+	// Correlated request handlers (CmdRequest frames)
+	requestHandlers sync.Map // map[uint32]*handlerEntry
+
+	// Room membership and topic subscriptions (keyed by pattern),
+	// cleaned up when a client disconnects
+	rooms              *groupRegistry
+	subscriptions      *groupRegistry
+	authorizeSubscribe AuthorizeSubscribeFn
 
 	// JSON-RPC handlers (converted to protocol messages internally)
-	jsonRPCHandlers sync.Map // map[string]func(params map[string]interface{}) (interface{}, error)
+	jsonRPCHandlers sync.Map // map[string]*handlerEntry
+
+	// Server-wide middleware, wrapped around every handler at dispatch time
+	middleware []knet.Middleware
+
+	// Handler failure reporting
+	onHandlerError OnHandlerErrorFn
+	panicPolicy    PanicPolicy
+
+	// Permission denial auditing
+	onPermissionDenied OnPermissionDeniedFn
+
+	// Translation of unknown JSON-RPC handler errors
+	jsonRPCErrorMapper JSONRPCErrorMapperFn
+
+	// Handler scheduling; pool is only set in DispatchWorkerPool mode
+	dispatch DispatchConfig
+	pool     *workerPool
+
+	// Graceful drain: handlers scheduled and not yet finished, and whether
+	// new connections are refused
+	inflight atomic.Int64
+	draining atomic.Bool
+
+	// Per-client send queue settings
+	sendQueue      *SendQueueConfig
+	onSlowConsumer OnSlowConsumerFn
 
 	// Rate limiting configuration
 	rateLimitConfig *RateLimitConfig
@@ -95,6 +220,8 @@ type Server struct {
 	upgrader     websocket.Upgrader
 	onConnect    OnConnectFn
 	onDisconnect OnClientDisconnectFn
+	authenticate AuthenticateFn
+	inBandAuth   *InBandAuthConfig
 }
 
 // New creates a new WebSocket server instance with the specified configuration.
@@ -121,40 +248,76 @@ func New(cfg *ServerConfig) *Server {
 	if cfg.RateLimitConfig == nil {
 		cfg.RateLimitConfig = DefaultRateLimitConfig()
 	}
+	if cfg.Path == "" {
+		cfg.Path = DefaultPath
+	}
+
+	var dispatch DispatchConfig
+	if cfg.Dispatch != nil {
+		dispatch = cfg.Dispatch.normalize()
+	}
+	var pool *workerPool
+	if dispatch.Mode == DispatchWorkerPool {
+		pool = newWorkerPool(dispatch.Workers, dispatch.QueueSize)
+	}
+
 	return &Server{
-		addr:            cfg.Addr,
-		rateLimitConfig: cfg.RateLimitConfig,
-		onConnect:       cfg.OnConnect,
-		onDisconnect:    cfg.OnClientDisconnect,
+		addr:               cfg.Addr,
+		path:               cfg.Path,
+		schema:             cfg.Schema,
+		tls:                cfg.tlsEnabled(),
+		tlsCfg:             cfg.TLSConfig,
+		certFile:           cfg.CertFile,
+		keyFile:            cfg.KeyFile,
+		rateLimitConfig:    cfg.RateLimitConfig,
+		onConnect:          cfg.OnConnect,
+		onDisconnect:       cfg.OnClientDisconnect,
+		authenticate:       cfg.Authenticate,
+		inBandAuth:         cfg.InBandAuth,
+		rooms:              newGroupRegistry(),
+		subscriptions:      newGroupRegistry(),
+		authorizeSubscribe: cfg.AuthorizeSubscribe,
+		onHandlerError:     cfg.OnHandlerError,
+		panicPolicy:        cfg.PanicPolicy,
+		onPermissionDenied: cfg.OnPermissionDenied,
+		jsonRPCErrorMapper: cfg.JSONRPCErrorMapper,
+		dispatch:           dispatch,
+		pool:               pool,
+		sendQueue:          cfg.SendQueue,
+		onSlowConsumer:     cfg.OnSlowConsumer,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin:     cfg.CheckOrigin,
+			Subprotocols:    cfg.Subprotocols,
 		},
 	}
 }
 
-// Start starts the WebSocket server
+// Start starts the WebSocket server on the configured address.
+// It binds the listener synchronously and serves connections in the background.
 func (s *Server) Start(ctx context.Context) error {
 	s.mu.Lock()
 	if s.running {
 		s.mu.Unlock()
 		return fmt.Errorf(knet.ErrServerAlreadyRunning)
 	}
-	s.running = true
-	s.mu.Unlock()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", s.handleWebSocket)
-
-	s.server = &http.Server{
-		Addr:    s.addr,
-		Handler: mux,
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.mu.Unlock()
+		return err
 	}
 
+	s.running = true
+	s.draining.Store(false)
+	s.server = s.newHTTPServer()
+	srv := s.server
+	s.mu.Unlock()
+
 	errChan := make(chan error, 1)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.serveListener(srv, ln); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
@@ -178,14 +341,73 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
-// Stop stops the WebSocket server
-func (s *Server) Stop(ctx context.Context) error {
+// Serve accepts connections on the given listener and blocks until the server is stopped.
+// The WebSocket endpoint is mounted on the configured path.
+// Returns nil after a successful Stop.
+func (s *Server) Serve(l net.Listener) error {
 	s.mu.Lock()
-	if !s.running {
+	if s.running {
 		s.mu.Unlock()
-		return nil
+		return fmt.Errorf(knet.ErrServerAlreadyRunning)
 	}
+	s.running = true
+	s.draining.Store(false)
+	s.server = s.newHTTPServer()
+	srv := s.server
+	s.mu.Unlock()
+
+	if err := s.serveListener(srv, l); err != nil && err != http.ErrServerClosed {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Handler returns the server as an http.Handler so it can be mounted in an existing router.
+func (s *Server) Handler() http.Handler {
+	return s
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handleWebSocket(w, r)
+}
+
+// newHTTPServer builds the http.Server used by Start and Serve
+func (s *Server) newHTTPServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(s.path, s)
+	if s.schema != nil && s.schema.Path != "" {
+		mux.Handle(strings.TrimSuffix(s.schema.Path, "/")+"/", s.SchemaHandler())
+	}
+
+	srv := &http.Server{
+		Addr:    s.addr,
+		Handler: mux,
+	}
+	if s.tlsCfg != nil {
+		srv.TLSConfig = s.tlsCfg.Clone()
+	}
+	return srv
+}
+
+// serveListener serves srv on l, terminating TLS when it is configured
+func (s *Server) serveListener(srv *http.Server, l net.Listener) error {
+	if s.tls {
+		return srv.ServeTLS(l, s.certFile, s.keyFile)
+	}
+	return srv.Serve(l)
+}
+
+// Stop stops the WebSocket server and closes all client connections.
+// Clients accepted through Handler are closed as well.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
 	s.running = false
+	srv := s.server
+	s.server = nil
 	s.mu.Unlock()
 
 	// Close all client connections
@@ -196,42 +418,137 @@ func (s *Server) Stop(ctx context.Context) error {
 		return true
 	})
 
-	if s.server != nil {
-		return s.server.Shutdown(ctx)
+	// Closing clients unblocks reads waiting for room in the queue
+	if s.pool != nil {
+		s.pool.stop()
+	}
+
+	if srv != nil {
+		return srv.Shutdown(ctx)
 	}
 	return nil
 }
 
 // RegisterHandler registers a handler for a specific command ID
 // The handler is executed asynchronously and receives the client and payload
-func (s *Server) RegisterHandler(ctx context.Context, commandID uint32, handler func(client knet.Client, payload []byte)) error {
-	s.handlers.Store(commandID, handler)
+// Command IDs in the reserved range (knet.CmdReservedMin and above) are rejected
+func (s *Server) RegisterHandler(ctx context.Context, commandID uint32, handler func(ctx context.Context, client knet.Client, payload []byte), opts ...knet.HandlerOption) error {
+	if commandID >= knet.CmdReservedMin {
+		return fmt.Errorf("%s: 0x%08X", knet.ErrReservedCommand, commandID)
+	}
+	s.handlers.Store(commandID, newHandlerEntry(func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+		handler(ctx, client, msg.Payload)
+		return nil, nil
+	}, opts))
+	return nil
+}
+
+// RegisterRequestHandler registers a correlated request handler for a specific command ID
+// The handler is executed asynchronously and its result is sent back as CmdResponse
+func (s *Server) RegisterRequestHandler(ctx context.Context, commandID uint32, handler func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error), opts ...knet.HandlerOption) error {
+	if commandID >= knet.CmdReservedMin {
+		return fmt.Errorf("%s: 0x%08X", knet.ErrReservedCommand, commandID)
+	}
+	s.requestHandlers.Store(commandID, newHandlerEntry(func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+		return handler(ctx, client, msg.Payload)
+	}, opts))
 	return nil
 }
 
 // RegisterJSONRPCHandler registers a JSON-RPC handler for a specific method
 // Internally, JSON-RPC requests are converted to protocol messages
 // This uses the reserved command ID net.CmdJSONRPC
-func (s *Server) RegisterJSONRPCHandler(ctx context.Context, method string, handler func(params map[string]interface{}) (interface{}, error)) error {
-	s.jsonRPCHandlers.Store(method, handler)
+func (s *Server) RegisterJSONRPCHandler(ctx context.Context, method string, handler func(ctx context.Context, params map[string]interface{}) (interface{}, error), opts ...knet.HandlerOption) error {
+	s.jsonRPCHandlers.Store(method, newHandlerEntry(func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+		var params map[string]interface{}
+		if len(msg.Payload) > 0 {
+			if err := json.Unmarshal(msg.Payload, &params); err != nil {
+				return nil, &knet.ParamsError{Method: method, Err: err}
+			}
+		}
+		return handler(ctx, params)
+	}, opts))
+	return nil
+}
+
+// RegisterJSONRPCRawHandler registers a JSON-RPC handler that decodes its own params,
+// which allows positional (array) params
+func (s *Server) RegisterJSONRPCRawHandler(ctx context.Context, method string, handler func(ctx context.Context, params json.RawMessage) (interface{}, error), opts ...knet.HandlerOption) error {
+	return s.RegisterJSONRPCMethod(ctx, method, func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+		return handler(ctx, json.RawMessage(msg.Payload))
+	}, opts...)
+}
+
+// RegisterJSONRPCMethod registers a JSON-RPC handler in the common handler shape,
+// giving it access to the calling client
+func (s *Server) RegisterJSONRPCMethod(ctx context.Context, method string, handler knet.Handler, opts ...knet.HandlerOption) error {
+	s.jsonRPCHandlers.Store(method, newHandlerEntry(handler, opts))
 	return nil
 }
 
+// Use appends server-wide middleware. It wraps every handler, including those
+// registered earlier, outside of any per-handler middleware.
+func (s *Server) Use(mw ...knet.Middleware) {
+	s.mu.Lock()
+	s.middleware = append(s.middleware, mw...)
+	s.mu.Unlock()
+}
+
 // handleWebSocket handles incoming WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		http.Error(w, knet.ErrServerDraining, http.StatusServiceUnavailable)
+		return
+	}
+
+	// Check the origin before authenticating, so cross-origin requests carrying
+	// a browser's cookies never reach the application's auth backend
+	if !s.checkOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	var principal any
+	if s.authenticate != nil {
+		var err error
+		if principal, err = s.authenticate(r); err != nil {
+			authErr := &knet.AuthError{}
+			errors.As(err, &authErr)
+			http.Error(w, authErr.Body(), authErr.StatusCode())
+			return
+		}
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Failed to upgrade connection", http.StatusBadRequest)
 		return
 	}
 
-	client := NewClient(conn, r.RemoteAddr, s.rateLimitConfig)
+	client := NewClient(conn, r.RemoteAddr, s.rateLimitConfig, s.sendQueue, s.onSlowConsumer)
+	client.handshake = knet.NewHandshake(r, conn.Subprotocol())
+	client.principal = principal
 	s.clients.Store(client.ID(), client)
 
 	// Start reading messages from client
 	go s.handleClient(client)
 }
 
+// checkOrigin applies the configured origin check, or the same-origin rule the
+// upgrader uses when none is configured
+func (s *Server) checkOrigin(r *http.Request) bool {
+	if s.upgrader.CheckOrigin != nil {
+		return s.upgrader.CheckOrigin(r)
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
 // handleClient handles messages from a connected client
 func (s *Server) handleClient(client *Client) {
 	defer func() {
@@ -242,8 +559,19 @@ func (s *Server) handleClient(client *Client) {
 		}
 		s.clients.Delete(client.ID())
 		client.Close(context.Background())
+		client.failPendingCalls()
+
+		// Rooms are left after OnDisconnect so the callback can still inspect
+		// membership (and attributes), and after Close so concurrent joins are rejected.
+		s.rooms.leaveAll(client.ID())
+		s.subscriptions.leaveAll(client.ID())
+		client.clearAttributes()
 	}()
 
+	// Create the per-client handler queue now; deferred after the cleanup above
+	// so the queue is closed first, as soon as reading stops
+	defer s.startClientQueue(client)()
+
 	// Set read deadline to prevent indefinite blocking
 	client.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 
@@ -260,12 +588,16 @@ func (s *Server) handleClient(client *Client) {
 		s.onConnect(client)
 	}
 
+	// Start the authentication deadline when in-band authentication is enabled
+	defer s.startAuth(client)()
+
 	for {
 		select {
 		case <-client.Context().Done():
 			return
 		default:
 			_, data, err := client.conn.ReadMessage()
+			receivedAt := time.Now()
 			if err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					fmt.Printf("Unexpected WebSocket close error: %v\n", err)
@@ -292,125 +624,73 @@ func (s *Server) handleClient(client *Client) {
 				return
 			}
 
+			frame := &knet.Message{
+				Kind:       knet.KindCommand,
+				CommandID:  commandID,
+				Payload:    payload,
+				Sequence:   client.sequence.Add(1),
+				ReceivedAt: receivedAt,
+			}
+
+			// Only the auth message is accepted until the client authenticates
+			if !client.authenticated.Load() {
+				s.handleAuthMessage(client, frame)
+				continue
+			}
+
 			// Handle the message
-			s.handleProtocolMessage(client, commandID, payload)
+			s.handleProtocolMessage(client, frame)
 		}
 	}
 }
 
 // handleProtocolMessage handles binary protocol messages
-// Handlers are executed in separate goroutines to avoid blocking the read loop
-func (s *Server) handleProtocolMessage(client *Client, commandID uint32, payload []byte) {
-	// Check if this is a JSON-RPC command (reserved command ID)
-	if commandID == knet.CmdJSONRPC {
-		// JSON-RPC also handled in goroutine
-		go s.handleJSONRPCMessage(client, payload)
-		return
-	}
-
-	// Handle normal protocol command
-	if handler, ok := s.handlers.Load(commandID); ok {
-		if handlerFunc, ok := handler.(func(knet.Client, []byte)); ok {
-			// Execute handler in goroutine (async, client decides if/when to respond)
-			go handlerFunc(client, payload)
+// Handlers are scheduled according to the dispatch mode to avoid blocking the read loop
+// The frame carries the sequence number and receive time of the message
+func (s *Server) handleProtocolMessage(client *Client, frame *knet.Message) {
+	// Check for reserved command IDs
+	switch frame.CommandID {
+	case knet.CmdJSONRPC:
+		// Responses to CallJSONRPC are routed inline like CmdResponse;
+		// requests are scheduled like any other handler
+		if s.handleJSONRPCResponse(client, frame.Payload) {
+			return
 		}
-	}
-	// Note: Unknown commands are silently ignored (fire-and-forget pattern)
-}
-
-// JSONRPCRequest represents a JSON-RPC 2.0 request
-type JSONRPCRequest struct {
-	JSONRPC string                 `json:"jsonrpc"`
-	Method  string                 `json:"method"`
-	Params  map[string]interface{} `json:"params,omitempty"`
-	ID      interface{}            `json:"id"`
-}
-
-// JSONRPCResponse represents a JSON-RPC 2.0 response
-type JSONRPCResponse struct {
-	JSONRPC string        `json:"jsonrpc"`
-	Result  interface{}   `json:"result,omitempty"`
-	Error   *JSONRPCError `json:"error,omitempty"`
-	ID      interface{}   `json:"id"`
-}
-
-// JSONRPCError represents a JSON-RPC 2.0 error
-type JSONRPCError struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-}
-
-// handleJSONRPCMessage handles JSON-RPC messages encoded in protocol format
-func (s *Server) handleJSONRPCMessage(client *Client, payload []byte) {
-	var req JSONRPCRequest
-	if err := json.Unmarshal(payload, &req); err != nil {
-		s.sendJSONRPCError(client, nil, knet.JSONRPCParseError, knet.ErrParseError, nil)
+		s.schedule(client, func() { s.handleJSONRPCMessage(client, frame) })
 		return
-	}
-
-	if req.JSONRPC != knet.JSONRPCVersion {
-		s.sendJSONRPCError(client, req.ID, knet.JSONRPCInvalidRequest, knet.ErrInvalidRequest, nil)
+	case knet.CmdSubscribe:
+		// Handled inline so subscribe/unsubscribe keep their arrival order
+		s.handleSubscribe(client, frame.Payload)
 		return
-	}
-
-	handler, ok := s.jsonRPCHandlers.Load(req.Method)
-	if !ok {
-		s.sendJSONRPCError(client, req.ID, knet.JSONRPCMethodNotFound, knet.ErrMethodNotFound, nil)
+	case knet.CmdUnsubscribe:
+		s.handleUnsubscribe(client, frame.Payload)
 		return
-	}
-
-	handlerFunc, ok := handler.(func(params map[string]interface{}) (interface{}, error))
-	if !ok {
-		s.sendJSONRPCError(client, req.ID, knet.JSONRPCInternalError, knet.ErrInternalError, nil)
+	case knet.CmdRequest:
+		s.handleRequestMessage(client, frame)
 		return
-	}
-
-	result, err := handlerFunc(req.Params)
-	if err != nil {
-		s.sendJSONRPCError(client, req.ID, knet.JSONRPCInternalError, err.Error(), nil)
+	case knet.CmdResponse:
+		s.handleResponseMessage(client, frame.Payload)
 		return
-	}
-
-	response := JSONRPCResponse{
-		JSONRPC: knet.JSONRPCVersion,
-		Result:  result,
-		ID:      req.ID,
-	}
-
-	responseData, err := json.Marshal(response)
-	if err != nil {
-		s.sendJSONRPCError(client, req.ID, knet.JSONRPCInternalError, knet.ErrInternalError, nil)
+	case knet.CmdResponseError:
+		s.handleResponseErrorMessage(client, frame.Payload)
 		return
 	}
 
-	// Send JSON-RPC response
-	client.Send(context.Background(), knet.CmdJSONRPC, responseData)
-}
-
-// sendJSONRPCError sends a JSON-RPC error response encoded in protocol format
-func (s *Server) sendJSONRPCError(client *Client, id interface{}, code int, message string, data interface{}) {
-	response := JSONRPCResponse{
-		JSONRPC: knet.JSONRPCVersion,
-		Error: &JSONRPCError{
-			Code:    code,
-			Message: message,
-			Data:    data,
-		},
-		ID: id,
-	}
-
-	responseData, err := json.Marshal(response)
-	if err != nil {
-		// Log error but continue - marshal errors are rare
-		fmt.Printf("Failed to marshal JSON-RPC error response: %v", err)
-		return
-	}
-
-	if err := client.Send(context.Background(), knet.CmdJSONRPC, responseData); err != nil {
-		// Log error - client may have disconnected
-		fmt.Printf("Failed to send JSON-RPC error response to client %s: %v", client.ID(), err)
+	// Handle normal protocol command
+	if entry, ok := s.handlers.Load(frame.CommandID); ok {
+		// Execute handler asynchronously (client decides if/when to respond)
+		s.schedule(client, func() {
+			_, err := s.invoke(client, frame, entry.(*handlerEntry))
+			if permErr, ok := s.permissionDenied(client, frame, err); ok {
+				s.sendPermissionDenied(client, permErr)
+				return
+			}
+			if err != nil && err != errHandlerPanicked {
+				s.reportHandlerError(&knet.HandlerError{ClientID: client.ID(), CommandID: frame.CommandID, Err: err})
+			}
+		})
 	}
+	// Note: Unknown commands are silently ignored (fire-and-forget pattern)
 }
 
 // GetClient returns a client by ID
@@ -431,13 +711,8 @@ func (s *Server) SendToClient(ctx context.Context, clientID string, commandID ui
 	return client.Send(ctx, commandID, payload)
 }
 
-// BroadcastCommand sends a command to all connected clients
-func (s *Server) BroadcastCommand(ctx context.Context, commandID uint32, payload []byte) error {
-	s.clients.Range(func(key, value interface{}) bool {
-		if client, ok := value.(*Client); ok {
-			client.Send(ctx, commandID, payload)
-		}
-		return true
-	})
-	return nil
+// BroadcastCommand sends a command to all connected clients without letting a
+// slow client block the others, and reports how many clients it reached
+func (s *Server) BroadcastCommand(ctx context.Context, commandID uint32, payload []byte, opts ...knet.BroadcastOption) (*knet.BroadcastReport, error) {
+	return s.broadcast(ctx, s.connectedClients(nil), commandID, payload, opts)
 }
@@ -0,0 +1,35 @@
+package knet
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// RegisterJSONRPC registers a JSON-RPC 2.0 method whose params are decoded
+// into Req and whose result is the returned Resp.
+//
+// Params that cannot be decoded into Req are answered with JSONRPCInvalidParams
+// (see ParamsError) without calling the handler. A request without params
+// gives the handler the zero Req. Use a struct for named params, and a slice
+// or array for positional params. Req and Resp are recorded as with WithTypes.
+//
+// Example:
+//
+//	type MoveParams struct {
+//	    X, Y int
+//	}
+//
+//	knet.RegisterJSONRPC(ctx, server, "player.move", func(ctx context.Context, client knet.Client, p MoveParams) (bool, error) {
+//	    return world.Move(client.ID(), p.X, p.Y), nil
+//	})
+func RegisterJSONRPC[Req, Resp any](ctx context.Context, server WebsocketServer, method string, handler func(ctx context.Context, client Client, params Req) (Resp, error), opts ...HandlerOption) error {
+	return server.RegisterJSONRPCMethod(ctx, method, func(ctx context.Context, client Client, msg *Message) (any, error) {
+		var params Req
+		if len(msg.Payload) > 0 {
+			if err := json.Unmarshal(msg.Payload, &params); err != nil {
+				return nil, &ParamsError{Method: method, Err: err}
+			}
+		}
+		return handler(ctx, client, params)
+	}, append([]HandlerOption{WithTypes[Req, Resp]()}, opts...)...)
+}
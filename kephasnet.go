@@ -1,6 +1,12 @@
 package knet
 
-import "context"
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+)
 
 // WebsocketServer defines the interface for a WebSocket server that uses binary protocol encoding.
 //
@@ -21,7 +27,7 @@ import "context"
 //	server := ws.New(config)
 //
 //	// Register a handler for command 0x01
-//	server.RegisterHandler(ctx, 0x01, func(client Client, payload []byte) {
+//	server.RegisterHandler(ctx, 0x01, func(ctx context.Context, client Client, payload []byte) {
 //	    response := []byte("response")
 //	    client.Send(ctx, 0x01, response)
 //	})
@@ -41,6 +47,76 @@ type WebsocketServer interface {
 	// Returns an error if there's a problem during shutdown.
 	Stop(ctx context.Context) error
 
+	// Drain gracefully shuts the server down, for example during a deploy.
+	//
+	// It refuses new WebSocket upgrades with 503, sends CmdGoingAway to every
+	// client (payload: hint, such as an alternative URL; may be empty), waits for
+	// in-flight handlers to finish and queued messages to be written, then closes
+	// every client with CloseGoingAway and stops the server.
+	//
+	// Messages received while draining are still handled. If ctx expires first,
+	// clients are closed anyway and ctx.Err() is returned.
+	//
+	// Example:
+	//
+	//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	//	defer cancel()
+	//	if err := server.Drain(ctx, "wss://eu-2.example.com/ws"); err != nil {
+	//	    log.Printf("drain incomplete: %v", err)
+	//	}
+	Drain(ctx context.Context, hint string) error
+
+	// Serve accepts incoming connections on the listener and blocks until the
+	// server is stopped. The WebSocket endpoint is mounted on the configured path
+	// (ServerConfig.Path, "/ws" by default).
+	//
+	// Use this to share a listener or to pick the port at runtime.
+	//
+	// Example:
+	//
+	//	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	//	go server.Serve(ln)
+	Serve(l net.Listener) error
+
+	// Handler returns an http.Handler that upgrades requests to WebSocket connections.
+	//
+	// It lets the server be mounted in an existing router or middleware stack
+	// instead of calling Start. The configured path is ignored; the handler
+	// serves whatever route it is mounted on. Stop still closes every client
+	// accepted through the handler.
+	//
+	// Example:
+	//
+	//	mux := http.NewServeMux()
+	//	mux.Handle("/realtime", server.Handler())
+	//	http.ListenAndServe(":8080", mux)
+	Handler() http.Handler
+
+	// OpenRPC generates an OpenRPC document describing the registered JSON-RPC
+	// methods, with the names, descriptions and types given through WithDoc,
+	// WithTypes and RegisterJSONRPC.
+	//
+	// Example:
+	//
+	//	doc, _ := json.MarshalIndent(server.OpenRPC(), "", "  ")
+	//	os.WriteFile("openrpc.json", doc, 0o644)
+	OpenRPC() *OpenRPCDocument
+
+	// CommandCatalog generates a catalog of the registered binary commands and
+	// correlated request handlers, similar to the OpenRPC document.
+	CommandCatalog() *CommandCatalog
+
+	// SchemaHandler returns an http.Handler serving the OpenRPC document at
+	// ".../openrpc.json" and the command catalog at ".../commands.json".
+	//
+	// Start and Serve mount it themselves when ServerConfig.Schema.Path is set;
+	// use it to mount the documents in an existing router.
+	//
+	// Example:
+	//
+	//	mux.Handle("/schema/", server.SchemaHandler())
+	SchemaHandler() http.Handler
+
 	// RegisterHandler registers a handler function for a specific command ID.
 	//
 	// The handler is executed asynchronously (fire-and-forget pattern).
@@ -51,19 +127,49 @@ type WebsocketServer interface {
 	// When a message with the given commandID is received from a client, the handler
 	// function is called with the client and payload in a separate goroutine.
 	//
+	// The handler context is derived from client.Context(): it is cancelled when the
+	// client disconnects, the server stops or the WithTimeout option expires, and it
+	// carries the message metadata (see MessageFromContext).
+	//
+	// Returns an error if commandID is in the reserved range (CmdReservedMin and above).
+	//
 	// Parameters:
 	//   - ctx: Context for cancellation
 	//   - commandID: The uint32 command identifier to handle
 	//   - handler: Function that processes the payload with access to the client
+	//   - opts: Optional per-handler settings such as WithMiddleware and WithTimeout
 	//
 	// Example:
 	//
-	//	server.RegisterHandler(ctx, 0x0100, func(client Client, payload []byte) {
+	//	server.RegisterHandler(ctx, 0x0100, func(ctx context.Context, client Client, payload []byte) {
 	//	    // Process message and optionally send response
 	//	    response := processMessage(payload)
 	//	    client.Send(ctx, 0x0100, response)
 	//	})
-	RegisterHandler(ctx context.Context, commandID uint32, handler func(client Client, payload []byte)) error
+	RegisterHandler(ctx context.Context, commandID uint32, handler func(ctx context.Context, client Client, payload []byte), opts ...HandlerOption) error
+
+	// RegisterRequestHandler registers a correlated request handler for a command ID.
+	//
+	// Unlike RegisterHandler, the handler returns a reply. Clients send correlated
+	// requests with CmdRequest (carrying the command ID and a request ID they pick);
+	// the server answers with CmdResponse carrying the same request ID, or with
+	// CmdResponseError when the handler fails. Return a *RequestError to control
+	// the error code; other errors are sent as RequestErrInternal.
+	//
+	// A command ID may have both a fire-and-forget handler and a request handler:
+	// plain frames go to the former, CmdRequest frames to the latter. Requests for
+	// a command without a request handler are answered with RequestErrUnknownCommand.
+	//
+	// Example:
+	//
+	//	server.RegisterRequestHandler(ctx, 0x0200, func(ctx context.Context, client Client, payload []byte) ([]byte, error) {
+	//	    balance, err := lookupBalance(payload)
+	//	    if err != nil {
+	//	        return nil, &RequestError{Code: RequestErrApplication, Message: "unknown account"}
+	//	    }
+	//	    return balance, nil
+	//	})
+	RegisterRequestHandler(ctx context.Context, commandID uint32, handler func(ctx context.Context, client Client, payload []byte) ([]byte, error), opts ...HandlerOption) error
 
 	// RegisterJSONRPCHandler registers a JSON-RPC 2.0 method handler.
 	//
@@ -74,33 +180,184 @@ type WebsocketServer interface {
 	// Parameters:
 	//   - ctx: Context for cancellation
 	//   - method: The JSON-RPC method name
-	//   - handler: Function that processes JSON-RPC params and returns a result
+	//   - handler: Function that processes JSON-RPC params and returns a result.
+	//     It receives a per-call context like RegisterHandler handlers do.
+	//   - opts: Optional per-handler settings such as WithMiddleware and WithTimeout
 	//
 	// Example:
 	//
-	//	server.RegisterJSONRPCHandler(ctx, "add", func(params map[string]interface{}) (interface{}, error) {
+	//	server.RegisterJSONRPCHandler(ctx, "add", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
 	//	    a := params["a"].(float64)
 	//	    b := params["b"].(float64)
 	//	    return a + b, nil
 	//	})
-	RegisterJSONRPCHandler(ctx context.Context, method string, handler func(params map[string]interface{}) (interface{}, error)) error
+	RegisterJSONRPCHandler(ctx context.Context, method string, handler func(ctx context.Context, params map[string]interface{}) (interface{}, error), opts ...HandlerOption) error
+
+	// RegisterJSONRPCRawHandler registers a JSON-RPC 2.0 method handler that
+	// receives the params undecoded.
+	//
+	// params is the raw JSON of the request's params member: an object for
+	// named params, an array for positional params, or nil when omitted.
+	// Handlers should return a *RPCError with code JSONRPCInvalidParams
+	// when params cannot be decoded.
+	//
+	// Example:
+	//
+	//	server.RegisterJSONRPCRawHandler(ctx, "subtract", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	//	    var args [2]float64
+	//	    if err := json.Unmarshal(params, &args); err != nil {
+	//	        return nil, &RPCError{Code: JSONRPCInvalidParams, Message: ErrInvalidParams}
+	//	    }
+	//	    return args[0] - args[1], nil
+	//	})
+	RegisterJSONRPCRawHandler(ctx context.Context, method string, handler func(ctx context.Context, params json.RawMessage) (interface{}, error), opts ...HandlerOption) error
+
+	// RegisterJSONRPCMethod registers a JSON-RPC 2.0 method as a Handler, which
+	// receives the calling client and the message; msg.Payload holds the raw params.
+	//
+	// It is the building block of RegisterJSONRPC, which most code should use
+	// instead. The returned value is encoded as the result.
+	RegisterJSONRPCMethod(ctx context.Context, method string, handler Handler, opts ...HandlerOption) error
+
+	// Use appends server-wide middleware.
+	//
+	// Middleware wraps every fire-and-forget, request and JSON-RPC handler,
+	// including handlers registered before Use was called. Server-wide
+	// middleware runs outside per-handler middleware (see WithMiddleware), and
+	// the first middleware passed is the outermost. A middleware can short-circuit
+	// a message by returning without calling next: a request is then answered
+	// with the returned error (or result), and a JSON-RPC call likewise.
+	//
+	// Example:
+	//
+	//	server.Use(func(next Handler) Handler {
+	//	    return func(ctx context.Context, client Client, msg *Message) (any, error) {
+	//	        start := time.Now()
+	//	        result, err := next(ctx, client, msg)
+	//	        log.Printf("cmd=0x%08X method=%s took=%s", msg.CommandID, msg.Method, time.Since(start))
+	//	        return result, err
+	//	    }
+	//	})
+	Use(mw ...Middleware)
 
 	// BroadcastCommand sends a command to all connected clients.
 	//
 	// This method is useful for broadcasting messages to all connected clients,
 	// such as chat messages, notifications, or system-wide updates.
 	//
+	// The frame is encoded once and queued for every client without waiting.
+	// Clients whose send queue is full are handled by the send queue policy;
+	// with the default blocking policy they are waited on in parallel until ctx
+	// ends, so one slow client does not hold up the rest.
+	//
+	// Returns a report of how many clients the message was delivered to,
+	// dropped for, or that were closed. An error is only returned when the
+	// message cannot be encoded.
+	//
 	// Parameters:
-	//   - ctx: Context for cancellation
+	//   - ctx: Context bounding how long to wait for clients with a full queue
 	//   - commandID: The uint32 command identifier
 	//   - payload: The binary payload to send
+	//   - opts: Optional settings such as WithClientErrors
 	//
 	// Example:
 	//
 	//	// Broadcast a notification to all clients
 	//	data, _ := json.Marshal(notification)
-	//	server.BroadcastCommand(ctx, 0x0100, data)
-	BroadcastCommand(ctx context.Context, commandID uint32, payload []byte) error
+	//	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	//	defer cancel()
+	//	report, err := server.BroadcastCommand(ctx, 0x0100, data)
+	//	log.Printf("delivered=%d dropped=%d closed=%d", report.Delivered, report.Dropped, report.Closed)
+	BroadcastCommand(ctx context.Context, commandID uint32, payload []byte, opts ...BroadcastOption) (*BroadcastReport, error)
+
+	// BroadcastWhere sends a command to every connected client for which match
+	// returns true. Delivery and the report work like BroadcastCommand.
+	//
+	// match runs once per connected client on the calling goroutine; keep it cheap.
+	//
+	// Example:
+	//
+	//	// Admin notices only reach clients holding the admin role
+	//	server.BroadcastWhere(ctx, 0x0500, notice, func(c knet.Client) bool {
+	//	    return isAdmin(c)
+	//	})
+	BroadcastWhere(ctx context.Context, commandID uint32, payload []byte, match func(client Client) bool, opts ...BroadcastOption) (*BroadcastReport, error)
+
+	// BroadcastExcept sends a command to every connected client except those whose
+	// IDs are listed in exclude. Delivery and the report work like BroadcastCommand,
+	// and the report always includes per-client errors, as with WithClientErrors.
+	//
+	// Example:
+	//
+	//	// "user X is typing" goes to everyone but the sender
+	//	server.BroadcastExcept(ctx, 0x0201, typing, client.ID())
+	BroadcastExcept(ctx context.Context, commandID uint32, payload []byte, exclude ...string) (*BroadcastReport, error)
+
+	// Join adds a connected client to a room.
+	//
+	// Rooms are created on first join and removed once their last member leaves.
+	// Clients are removed from every room automatically when they disconnect,
+	// after OnDisconnect has run. Joining the same room twice is a no-op.
+	//
+	// Returns an error if the room name is empty or the client is not connected
+	// to this server.
+	//
+	// Example:
+	//
+	//	server.Join(client, "lobby")
+	Join(client Client, room string) error
+
+	// Leave removes a client from a room. Leaving a room the client is not in is a no-op.
+	Leave(client Client, room string) error
+
+	// BroadcastToRoom sends a command to every member of a room.
+	//
+	// Clients whose IDs are listed in exclude are skipped, which is useful to
+	// avoid echoing a message back to its sender. Delivery and the report work
+	// like BroadcastCommand: a slow member does not hold up the others. The
+	// report always includes per-client errors, as with WithClientErrors.
+	//
+	// Example:
+	//
+	//	server.BroadcastToRoom(ctx, "lobby", 0x0200, msg, client.ID())
+	BroadcastToRoom(ctx context.Context, room string, commandID uint32, payload []byte, exclude ...string) (*BroadcastReport, error)
+
+	// RoomMembers returns a snapshot of the clients currently in a room.
+	RoomMembers(room string) []Client
+
+	// ClientRooms returns the rooms a client belongs to, sorted by name.
+	ClientRooms(client Client) []string
+
+	// Rooms returns the names of all rooms that currently have members, sorted by name.
+	Rooms() []string
+
+	// Subscribe subscribes a connected client to a topic pattern from the server side.
+	//
+	// Topics are dot-separated tokens such as "orders.eu.created". Patterns may use
+	// "*" to match exactly one token ("orders.eu.*") and ">" as the last token to
+	// match one or more trailing tokens ("metrics.>").
+	//
+	// Clients subscribe themselves by sending CmdSubscribe with the pattern as
+	// payload; those requests go through ServerConfig.AuthorizeSubscribe, while
+	// this method bypasses it. Subscriptions are removed when the client disconnects.
+	Subscribe(client Client, pattern string) error
+
+	// Unsubscribe removes a client's subscription to a topic pattern.
+	Unsubscribe(client Client, pattern string) error
+
+	// Subscriptions returns the topic patterns a client is subscribed to, sorted.
+	Subscriptions(client Client) []string
+
+	// Publish sends a command to every client subscribed to a pattern matching topic.
+	//
+	// The topic must be concrete (no wildcards). Clients with several matching
+	// subscriptions receive the message once. Delivery and the report work like
+	// BroadcastCommand.
+	//
+	// Example:
+	//
+	//	server.Publish(ctx, "orders.eu.created", 0x0400, orderJSON)
+	Publish(ctx context.Context, topic string, commandID uint32, payload []byte, opts ...BroadcastOption) (*BroadcastReport, error)
 }
 
 // Client represents a connected WebSocket client.
@@ -130,6 +387,57 @@ type Client interface {
 	// This is typically in the format "IP:port", for example "192.168.1.100:54321".
 	RemoteAddr() string
 
+	// PeerCertificate returns the verified TLS client certificate of the connection.
+	//
+	// It is only set when the server requires and verifies client certificates
+	// (mutual TLS). Returns nil for plain connections or unverified peers.
+	//
+	// Example:
+	//
+	//	if cert := client.PeerCertificate(); cert != nil {
+	//	    deviceID := cert.Subject.CommonName
+	//	}
+	PeerCertificate() *x509.Certificate
+
+	// Handshake returns an immutable snapshot of the HTTP request that was
+	// upgraded to this connection: headers, URL, cookies, TLS state and the
+	// negotiated subprotocol (see ServerConfig.Subprotocols).
+	//
+	// Example:
+	//
+	//	token := client.Handshake().Query().Get("token")
+	//	requestID := client.Handshake().HeaderValue("X-Request-ID")
+	Handshake() *Handshake
+
+	// Principal returns the identity attached to the connection by
+	// authentication (ServerConfig.Authenticate), or nil when unauthenticated.
+	//
+	// Example:
+	//
+	//	if user, ok := client.Principal().(*User); ok {
+	//	    log.Printf("%s connected", user.Name)
+	//	}
+	Principal() any
+
+	// Set stores a value on the connection under key, replacing any previous value.
+	//
+	// The attribute store is safe for concurrent use and lives as long as the
+	// connection: it is cleared after OnDisconnect has run. Use it to attach user
+	// identity, locale or game state from OnConnect, middleware or handlers.
+	// Read values back with Get, or typed with Value and ValueOr.
+	//
+	// Example:
+	//
+	//	client.Set("user", &User{Name: "alice"})
+	//	user, ok := knet.Value[*User](client, "user")
+	Set(key string, value any)
+
+	// Get returns the value stored under key and whether it was present.
+	Get(key string) (any, bool)
+
+	// Delete removes the value stored under key. Deleting a missing key is a no-op.
+	Delete(key string)
+
 	// Context returns the client's lifecycle context.
 	//
 	// This context is automatically cancelled when the connection closes,
@@ -147,9 +455,11 @@ type Client interface {
 	// Send sends binary data to the client over the WebSocket connection.
 	//
 	// The command and payload are automatically encoded using the protocol format
-	// before being sent. The send operation is non-blocking and queued for delivery.
+	// and queued for delivery. When the client's send queue is full, the server's
+	// send queue policy applies: by default Send blocks until there is room.
 	//
-	// Returns an error if the connection is closed or the context is cancelled.
+	// Returns an error if the connection is closed, the context is cancelled, or
+	// the message is dropped (or the client disconnected) because the queue is full.
 	//
 	// Example:
 	//
@@ -158,6 +468,46 @@ type Client interface {
 	//	}
 	Send(ctx context.Context, command uint32, payload []byte) error
 
+	// Call sends a correlated request to the client and waits for its reply.
+	//
+	// The request travels as CmdRequest with a request ID allocated by the server;
+	// the client must answer with CmdResponse (or CmdResponseError) carrying the
+	// same request ID. Returns the reply payload, a *RequestError when the client
+	// answers with an error frame, ctx.Err() when the context ends first, or an
+	// error when the client disconnects while the call is pending.
+	//
+	// Example:
+	//
+	//	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	//	defer cancel()
+	//	answer, err := client.Call(ctx, 0x0300, []byte("confirm delete?"))
+	Call(ctx context.Context, command uint32, payload []byte) ([]byte, error)
+
+	// NotifyJSONRPC sends a JSON-RPC 2.0 notification to the client over CmdJSONRPC.
+	//
+	// params must encode to a JSON object or array, or be nil to omit them.
+	// Notifications carry no id and are never answered.
+	//
+	// Example:
+	//
+	//	client.NotifyJSONRPC(ctx, "chat.message", map[string]string{"from": "bob", "text": "hi"})
+	NotifyJSONRPC(ctx context.Context, method string, params any) error
+
+	// CallJSONRPC sends a JSON-RPC 2.0 request to the client over CmdJSONRPC and
+	// waits for its response.
+	//
+	// The request id is allocated by the server and the response is routed back
+	// by the read loop. The response's result is decoded into result, which may
+	// be nil. Returns a *RPCError when the client answers with an error object,
+	// ctx.Err() when the context ends first, or an error when the client
+	// disconnects while the call is pending.
+	//
+	// Example:
+	//
+	//	var confirmed bool
+	//	err := client.CallJSONRPC(ctx, "ui.confirm", map[string]string{"text": "Delete?"}, &confirmed)
+	CallJSONRPC(ctx context.Context, method string, params, result any) error
+
 	// Close closes the client connection gracefully.
 	//
 	// This is equivalent to calling CloseWithCode with websocket.CloseNormalClosure.
@@ -0,0 +1,101 @@
+package knet
+
+import "reflect"
+
+// OpenRPCVersion is the OpenRPC specification version of generated documents.
+const OpenRPCVersion = "1.2.6"
+
+// Schema is a JSON Schema object describing a request or response type.
+type Schema map[string]any
+
+// OpenRPCDocument describes the JSON-RPC methods of a server, following the
+// OpenRPC specification (https://spec.open-rpc.org).
+type OpenRPCDocument struct {
+	OpenRPC    string             `json:"openrpc"`
+	Info       OpenRPCInfo        `json:"info"`
+	Methods    []OpenRPCMethod    `json:"methods"`
+	Components *OpenRPCComponents `json:"components,omitempty"`
+}
+
+// OpenRPCInfo is the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenRPCMethod describes one JSON-RPC method.
+type OpenRPCMethod struct {
+	Name string `json:"name"`
+	// Summary is the name given with WithDoc
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Params lists the struct fields of the params type (by-name), the
+	// elements of an array type (by-position), or a single "params" entry
+	Params         []ContentDescriptor `json:"params"`
+	Result         *ContentDescriptor  `json:"result,omitempty"`
+	ParamStructure string              `json:"paramStructure,omitempty"`
+}
+
+// ContentDescriptor describes a param or result of an OpenRPC method.
+type ContentDescriptor struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of the named types referenced with
+// "$ref": "#/components/schemas/<Name>".
+type OpenRPCComponents struct {
+	Schemas map[string]Schema `json:"schemas,omitempty"`
+}
+
+// CommandCatalog describes the binary commands of a server. Request and
+// response schemas describe the payload once decoded, typically as JSON.
+type CommandCatalog struct {
+	Commands []CommandDescriptor `json:"commands"`
+	// Schemas holds the named types referenced with "$ref": "#/schemas/<Name>"
+	Schemas map[string]Schema `json:"schemas,omitempty"`
+}
+
+// CommandDescriptor describes one registered command.
+type CommandDescriptor struct {
+	ID uint32 `json:"id"`
+	// Kind is "command" for RegisterHandler and "request" for RegisterRequestHandler
+	Kind        string `json:"kind"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Request     Schema `json:"request,omitempty"`
+	Response    Schema `json:"response,omitempty"`
+}
+
+// WithDoc names and describes the handler being registered in the generated
+// OpenRPC document and command catalog. For JSON-RPC methods, name is the
+// method summary.
+//
+// Example:
+//
+//	server.RegisterHandler(ctx, 0x0200, handleChat, knet.WithDoc("chat.send", "Sends a message to the room"))
+func WithDoc(name, description string) HandlerOption {
+	return func(o *HandlerOptions) {
+		o.Name = name
+		o.Description = description
+	}
+}
+
+// WithTypes records the request and response types of the handler being
+// registered, so the generated documents include their JSON Schemas.
+//
+// Resp is the result of requests and JSON-RPC methods, or what a
+// fire-and-forget handler sends back. Use struct{} when there is none.
+// RegisterJSONRPC records its types automatically.
+//
+// Example:
+//
+//	server.RegisterRequestHandler(ctx, 0x0300, lookupBalance, knet.WithTypes[BalanceQuery, Balance]())
+func WithTypes[Req, Resp any]() HandlerOption {
+	return func(o *HandlerOptions) {
+		o.RequestType = reflect.TypeFor[Req]()
+		o.ResponseType = reflect.TypeFor[Resp]()
+	}
+}
@@ -0,0 +1,77 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdSessionInfo uint32 = 0x00D0
+
+type sessionUser struct {
+	Name string
+}
+
+func TestClientAttributes(t *testing.T) {
+	t.Parallel()
+
+	disconnected := make(chan knet.Client, 1)
+	seenOnDisconnect := make(chan bool, 1)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		client.Set("user", &sessionUser{Name: "alice"})
+		client.Set("locale", "pt-BR")
+	}, func(client knet.Client, voluntary bool) {
+		_, ok := knet.Value[*sessionUser](client, "user")
+		seenOnDisconnect <- ok
+		disconnected <- client
+	})
+	server := ws.New(cfg)
+
+	server.RegisterHandler(context.Background(), cmdSessionInfo, func(ctx context.Context, client knet.Client, payload []byte) {
+		user, ok := knet.Value[*sessionUser](client, "user")
+		if !ok {
+			client.Send(ctx, cmdSessionInfo, []byte("anonymous"))
+			return
+		}
+
+		// Wrong types and missing keys fall back
+		if _, ok := knet.Value[int](client, "locale"); ok {
+			client.Send(ctx, cmdSessionInfo, []byte("locale typed as int"))
+			return
+		}
+		client.Delete("locale")
+		locale := knet.ValueOr(client, "locale", "en-US")
+
+		client.Send(ctx, cmdSessionInfo, []byte(user.Name+"/"+locale))
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	writeCommand(t, conn, cmdSessionInfo, nil)
+	if _, payload := readCommand(t, conn); string(payload) != "alice/en-US" {
+		t.Errorf("payload = %q, want %q", payload, "alice/en-US")
+	}
+
+	conn.Close()
+	select {
+	case client := <-disconnected:
+		if !<-seenOnDisconnect {
+			t.Error("attributes should still be readable in OnDisconnect")
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, ok := client.Get("user"); !ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("attributes were not cleared after disconnect")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+}
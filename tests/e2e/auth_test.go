@@ -0,0 +1,101 @@
+package e2e_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+func TestAuthenticateBeforeUpgrade(t *testing.T) {
+	t.Parallel()
+
+	principals := make(chan any, 1)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		principals <- client.Principal()
+	}, nil)
+	cfg.Authenticate = func(r *http.Request) (any, error) {
+		switch r.URL.Query().Get("token") {
+		case "good":
+			return "alice", nil
+		case "banned":
+			return nil, &knet.AuthError{Status: http.StatusForbidden, Message: "account suspended"}
+		default:
+			return nil, errors.New("missing token")
+		}
+	}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	t.Run("accepted", func(t *testing.T) {
+		dial(t, url+"?token=good")
+		if principal := <-principals; principal != "alice" {
+			t.Errorf("Principal() = %v, want alice", principal)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing token", "", http.StatusUnauthorized, "Unauthorized"},
+		{"forbidden", "banned", http.StatusForbidden, "account suspended"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := newDialer().Dial(url+"?token="+tt.token, nil)
+			if err == nil || resp == nil {
+				t.Fatalf("Dial() = %v, %v, want HTTP rejection", resp, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAfterOriginCheck(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	cfg := ws.NewConfig("", ws.NoRateLimit(), func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://game.example"
+	}, nil, nil)
+	cfg.Authenticate = func(r *http.Request) (any, error) {
+		calls.Add(1)
+		return "alice", nil
+	}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	_, resp, err := newDialer().Dial(url, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Dial() = %v, %v, want 403", resp, err)
+	}
+	resp.Body.Close()
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Authenticate called %d times for a rejected origin, want 0", n)
+	}
+
+	conn, _, err := newDialer().Dial(url, http.Header{"Origin": {"https://game.example"}})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	conn.Close()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Authenticate called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,101 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdFlood uint32 = 0x00C0
+
+// slowConsumerEvent is one OnSlowConsumer invocation
+type slowConsumerEvent struct {
+	policy    ws.SendQueuePolicy
+	commandID uint32
+}
+
+// floodSlowConsumer connects a client that never reads and sends it large
+// messages until the server reports a slow consumer. It returns the first
+// event, the client and the error of the Send that triggered it.
+func floodSlowConsumer(t *testing.T, policy ws.SendQueuePolicy) (slowConsumerEvent, knet.Client, error) {
+	t.Helper()
+
+	events := make(chan slowConsumerEvent, 64)
+	connected := make(chan knet.Client, 1)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil)
+	cfg.SendQueue = &ws.SendQueueConfig{Size: 2, Policy: policy}
+	cfg.OnSlowConsumer = func(client knet.Client, policy ws.SendQueuePolicy, commandID uint32) {
+		events <- slowConsumerEvent{policy: policy, commandID: commandID}
+	}
+	server := ws.New(cfg)
+
+	// The connection is never read, so socket buffers fill up and the queue backs up
+	dial(t, serveOnRandomPort(t, server)+"/ws")
+	client := <-connected
+
+	payload := make([]byte, 256*1024)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		err := client.Send(context.Background(), cmdFlood, payload)
+		select {
+		case event := <-events:
+			return event, client, err
+		default:
+		}
+		if err != nil {
+			t.Fatalf("Send() error = %v before a slow consumer was reported", err)
+		}
+	}
+	t.Fatal("OnSlowConsumer was not called")
+	return slowConsumerEvent{}, nil, nil
+}
+
+func TestSendQueueDropNewest(t *testing.T) {
+	t.Parallel()
+
+	event, client, err := floodSlowConsumer(t, ws.SendQueueDropNewest)
+	if event.policy != ws.SendQueueDropNewest || event.commandID != cmdFlood {
+		t.Errorf("event = %+v, want drop newest of 0x%x", event, cmdFlood)
+	}
+	if err == nil {
+		t.Error("Send() should fail when the message is dropped")
+	}
+	if !client.IsAlive() {
+		t.Error("client should stay connected when messages are dropped")
+	}
+}
+
+func TestSendQueueDropOldest(t *testing.T) {
+	t.Parallel()
+
+	event, client, err := floodSlowConsumer(t, ws.SendQueueDropOldest)
+	if event.policy != ws.SendQueueDropOldest {
+		t.Errorf("policy = %v, want SendQueueDropOldest", event.policy)
+	}
+	if err != nil {
+		t.Errorf("Send() error = %v, want nil (the new message is queued)", err)
+	}
+	if !client.IsAlive() {
+		t.Error("client should stay connected when messages are dropped")
+	}
+}
+
+func TestSendQueueDisconnect(t *testing.T) {
+	t.Parallel()
+
+	event, client, err := floodSlowConsumer(t, ws.SendQueueDisconnect)
+	if event.policy != ws.SendQueueDisconnect {
+		t.Errorf("policy = %v, want SendQueueDisconnect", event.policy)
+	}
+	if err == nil {
+		t.Error("Send() should fail when the client is disconnected")
+	}
+	if client.IsAlive() {
+		t.Error("slow consumer should be disconnected")
+	}
+}
@@ -19,7 +19,7 @@ func TestBasicEcho(t *testing.T) {
 
 	const cmdEcho uint32 = 0x0001
 	// Handler receives client and payload, sends response asynchronously
-	server.RegisterHandler(ctx, cmdEcho, func(client knet.Client, payload []byte) {
+	server.RegisterHandler(ctx, cmdEcho, func(ctx context.Context, client knet.Client, payload []byte) {
 		// Echo back to the client
 		client.Send(context.Background(), cmdEcho, payload)
 	})
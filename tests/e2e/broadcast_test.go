@@ -0,0 +1,201 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdAnnounce uint32 = 0x00C1
+
+func TestBroadcastCommandReport(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 2)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	alice, bob := dial(t, url), dial(t, url)
+	<-connected
+	<-connected
+
+	report, err := server.BroadcastCommand(context.Background(), cmdAnnounce, []byte("hello"))
+	if err != nil {
+		t.Fatalf("BroadcastCommand() error = %v", err)
+	}
+	if report.Delivered != 2 || report.Dropped != 0 || report.Closed != 0 {
+		t.Errorf("report = %+v, want 2 delivered", report)
+	}
+	if report.Errors != nil {
+		t.Errorf("Errors = %v, want nil without WithClientErrors", report.Errors)
+	}
+
+	for name, conn := range map[string]*websocket.Conn{"alice": alice, "bob": bob} {
+		if cmd, payload := readCommand(t, conn); cmd != cmdAnnounce || string(payload) != "hello" {
+			t.Errorf("%s got (0x%x, %q), want announcement", name, cmd, payload)
+		}
+	}
+}
+
+func TestBroadcastCommandSlowClient(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 2)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil)
+	cfg.SendQueue = &ws.SendQueueConfig{Size: 2, Policy: ws.SendQueueBlock}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	// The slow client never reads; fill its queue until Send would block
+	dial(t, url)
+	slow := <-connected
+	payload := make([]byte, 256*1024)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := slow.Send(ctx, cmdFlood, payload)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	fast := dial(t, url)
+	<-connected
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report, err := server.BroadcastCommand(ctx, cmdAnnounce, []byte("hello"), knet.WithClientErrors())
+	if err != nil {
+		t.Fatalf("BroadcastCommand() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("BroadcastCommand() took %s, want bounded by ctx", elapsed)
+	}
+
+	if report.Delivered != 1 || report.Dropped != 1 || report.Closed != 0 {
+		t.Errorf("report = %+v, want 1 delivered and 1 dropped", report)
+	}
+	if err := report.Errors[slow.ID()]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow client error = %v, want deadline exceeded", err)
+	}
+	if cmd, payload := readCommand(t, fast); cmd != cmdAnnounce || string(payload) != "hello" {
+		t.Errorf("fast client got (0x%x, %q), want announcement", cmd, payload)
+	}
+}
+
+func TestBroadcastCommandDisconnectsSlowClientsWithoutWaiting(t *testing.T) {
+	t.Parallel()
+
+	const slowClients = 4
+	connected := make(chan knet.Client, slowClients+1)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil)
+	cfg.SendQueue = &ws.SendQueueConfig{Size: 1, Policy: ws.SendQueueDisconnect}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	// Slow clients never read and use a tiny receive buffer, so their write
+	// pump gets stuck on a large frame; one more message fills their queue
+	dialer := newDialer()
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err == nil {
+			conn.(*net.TCPConn).SetReadBuffer(4096)
+		}
+		return conn, err
+	}
+
+	payload := make([]byte, 8*1024*1024)
+	for i := 0; i < slowClients; i++ {
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+		slow := <-connected
+		if err := slow.Send(context.Background(), cmdFlood, payload); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if err := slow.Send(context.Background(), cmdFlood, nil); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	fast := dial(t, url)
+	<-connected
+
+	start := time.Now()
+	report, err := server.BroadcastCommand(context.Background(), cmdAnnounce, []byte("hello"))
+	if err != nil {
+		t.Fatalf("BroadcastCommand() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("BroadcastCommand() took %s, want slow clients closed without waiting", elapsed)
+	}
+
+	if report.Delivered != 1 || report.Closed != slowClients {
+		t.Errorf("report = %+v, want 1 delivered and %d closed", report, slowClients)
+	}
+	if cmd, payload := readCommand(t, fast); cmd != cmdAnnounce || string(payload) != "hello" {
+		t.Errorf("fast client got (0x%x, %q), want announcement", cmd, payload)
+	}
+}
+
+func TestBroadcastWhereAndExcept(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 3)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	conns := make(map[string]*websocket.Conn)
+	var sender string
+	for i := 0; i < 3; i++ {
+		conn := dial(t, url)
+		client := <-connected
+		conns[client.ID()] = conn
+		sender = client.ID()
+	}
+	ctx := context.Background()
+
+	report, err := server.BroadcastExcept(ctx, cmdAnnounce, []byte("typing"), sender)
+	if err != nil || report.Delivered != 2 || report.Errors == nil || len(report.Errors) != 0 {
+		t.Fatalf("BroadcastExcept() = %+v, %v, want 2 delivered and an empty error map", report, err)
+	}
+
+	report, err = server.BroadcastWhere(ctx, cmdAnnounce, []byte("notice"), func(client knet.Client) bool {
+		return client.ID() == sender
+	})
+	if err != nil || report.Delivered != 1 {
+		t.Fatalf("BroadcastWhere() = %+v, %v, want 1 delivered", report, err)
+	}
+
+	for id, conn := range conns {
+		want := "typing"
+		if id == sender {
+			// The sender skipped the first broadcast, so the notice comes first
+			want = "notice"
+		}
+		if _, payload := readCommand(t, conn); string(payload) != want {
+			t.Errorf("client %s got %q, want %q", id, payload, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdConfirm uint32 = 0x0060
+
+func TestServerInitiatedCall(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 1)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	client := <-connected
+
+	type outcome struct {
+		payload []byte
+		err     error
+	}
+	call := func() <-chan outcome {
+		done := make(chan outcome, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			payload, err := client.Call(ctx, cmdConfirm, []byte("delete?"))
+			done <- outcome{payload, err}
+		}()
+		return done
+	}
+
+	// readRequest reads the CmdRequest sent by Call and returns its request ID
+	readRequest := func() uint32 {
+		t.Helper()
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdRequest {
+			t.Fatalf("command = 0x%x, want CmdRequest", cmd)
+		}
+		commandID, requestID, payload, err := protocol.DecodeRequest(body)
+		if err != nil || commandID != cmdConfirm || string(payload) != "delete?" {
+			t.Fatalf("request = (0x%x, %q, %v), want (0x%x, %q)", commandID, payload, err, cmdConfirm, "delete?")
+		}
+		return requestID
+	}
+
+	t.Run("reply", func(t *testing.T) {
+		done := call()
+		requestID := readRequest()
+		// A JSON-RPC response with the same id does not resolve the binary call
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":"no","id":%d}`, requestID)))
+		writeCommand(t, conn, knet.CmdResponse, protocol.EncodeResponse(requestID, []byte("yes")))
+
+		result := <-done
+		if result.err != nil || string(result.payload) != "yes" {
+			t.Errorf("Call() = (%q, %v), want (%q, nil)", result.payload, result.err, "yes")
+		}
+	})
+
+	t.Run("error reply", func(t *testing.T) {
+		done := call()
+		writeCommand(t, conn, knet.CmdResponseError, protocol.EncodeError(readRequest(), knet.RequestErrApplication, "denied"))
+
+		var reqErr *knet.RequestError
+		if result := <-done; !errors.As(result.err, &reqErr) || reqErr.Code != knet.RequestErrApplication || reqErr.Message != "denied" {
+			t.Errorf("Call() error = %v, want RequestError{%d, denied}", result.err, knet.RequestErrApplication)
+		}
+	})
+
+	t.Run("disconnect aborts pending call", func(t *testing.T) {
+		done := call()
+		readRequest()
+		conn.Close()
+
+		select {
+		case result := <-done:
+			if result.err == nil {
+				t.Error("expected pending Call() to fail after disconnect")
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("pending Call() was not aborted on disconnect")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdMeta    uint32 = 0x00A0
+	cmdBlock   uint32 = 0x00A1
+	cmdTimeout uint32 = 0x00A2
+)
+
+func TestHandlerContext(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+	ctx := context.Background()
+
+	messages := make(chan *knet.Message, 4)
+	server.RegisterHandler(ctx, cmdMeta, func(ctx context.Context, client knet.Client, payload []byte) {
+		msg, _ := knet.MessageFromContext(ctx)
+		messages <- msg
+	})
+	server.RegisterJSONRPCHandler(ctx, "meta", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		msg, _ := knet.MessageFromContext(ctx)
+		messages <- msg
+		return nil, nil
+	})
+
+	cancelled := make(chan error, 1)
+	server.RegisterHandler(ctx, cmdBlock, func(ctx context.Context, client knet.Client, payload []byte) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+	})
+	server.RegisterRequestHandler(ctx, cmdTimeout, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, knet.WithTimeout(50*time.Millisecond))
+
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	t.Run("carries message metadata", func(t *testing.T) {
+		conn := dial(t, url)
+		before := time.Now()
+
+		writeCommand(t, conn, cmdMeta, nil)
+		request, _ := json.Marshal(map[string]interface{}{"jsonrpc": knet.JSONRPCVersion, "method": "meta", "id": 1})
+		writeCommand(t, conn, knet.CmdJSONRPC, request)
+
+		first, second := <-messages, <-messages
+		if first.Sequence > second.Sequence {
+			// Handlers run concurrently in the default dispatch mode
+			first, second = second, first
+		}
+		if first.CommandID != cmdMeta || first.Sequence != 1 || first.ReceivedAt.Before(before) {
+			t.Errorf("command message = %+v, want command 0x%x with sequence 1", first, cmdMeta)
+		}
+		if second.Method != "meta" || second.Sequence != 2 || second.Kind != knet.KindJSONRPC {
+			t.Errorf("JSON-RPC message = %+v, want method %q with sequence 2", second, "meta")
+		}
+	})
+
+	t.Run("cancelled on disconnect", func(t *testing.T) {
+		conn := dial(t, url)
+		writeCommand(t, conn, cmdBlock, nil)
+		syncReader(t, conn)
+		conn.Close()
+
+		select {
+		case err := <-cancelled:
+			if err != context.Canceled {
+				t.Errorf("ctx.Err() = %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+	})
+
+	t.Run("per-command timeout", func(t *testing.T) {
+		conn := dial(t, url)
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdTimeout, 1, nil))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		if _, _, message, _ := protocol.DecodeError(body); message != context.DeadlineExceeded.Error() {
+			t.Errorf("message = %q, want %q", message, context.DeadlineExceeded.Error())
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdSeq  uint32 = 0x0090
+	cmdSlow uint32 = 0x0091
+)
+
+// syncReader waits until the server has read every frame sent before it, using
+// the inline subscribe acknowledgement as a barrier
+func syncReader(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+
+	writeCommand(t, conn, knet.CmdSubscribe, []byte("barrier"))
+	if cmd, _ := readCommand(t, conn); cmd != knet.CmdSubscribe {
+		t.Fatalf("command = 0x%x, want subscribe ack", cmd)
+	}
+}
+
+func TestPerClientDispatchPreservesOrder(t *testing.T) {
+	t.Parallel()
+
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.Dispatch = &ws.DispatchConfig{Mode: ws.DispatchPerClient}
+	server := ws.New(cfg)
+
+	const count = 50
+	var mu sync.Mutex
+	var got []uint32
+	done := make(chan struct{})
+	server.RegisterHandler(context.Background(), cmdSeq, func(ctx context.Context, client knet.Client, payload []byte) {
+		// Random delays would reorder handlers running concurrently
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, binary.BigEndian.Uint32(payload))
+		if len(got) == count {
+			close(done)
+		}
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	for i := uint32(0); i < count; i++ {
+		writeCommand(t, conn, cmdSeq, binary.BigEndian.AppendUint32(nil, i))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handlers did not finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, seq := range got {
+		if seq != uint32(i) {
+			t.Fatalf("handled %v, want arrival order", got)
+		}
+	}
+}
+
+func TestWorkerPoolQueueFull(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(policy ws.QueueFullPolicy) (knet.WebsocketServer, *atomic.Int32, chan struct{}, chan struct{}) {
+		cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+		cfg.Dispatch = &ws.DispatchConfig{Mode: ws.DispatchWorkerPool, Workers: 1, QueueSize: 1, QueueFullPolicy: policy}
+		server := ws.New(cfg)
+
+		handled := &atomic.Int32{}
+		started := make(chan struct{}, 8)
+		release := make(chan struct{})
+		server.RegisterHandler(context.Background(), cmdSlow, func(ctx context.Context, client knet.Client, payload []byte) {
+			started <- struct{}{}
+			<-release
+			handled.Add(1)
+		})
+		return server, handled, started, release
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		t.Parallel()
+
+		server, handled, started, release := newServer(ws.QueueFullDrop)
+		conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+		// Occupy the only worker, then fill the queue and overflow it
+		writeCommand(t, conn, cmdSlow, nil)
+		<-started
+		for i := 0; i < 4; i++ {
+			writeCommand(t, conn, cmdSlow, nil)
+		}
+		syncReader(t, conn)
+		close(release)
+
+		<-started
+		time.Sleep(50 * time.Millisecond)
+		if n := handled.Load(); n != 2 {
+			t.Errorf("handled = %d, want 2 (one running, one queued)", n)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		t.Parallel()
+
+		server, _, started, release := newServer(ws.QueueFullDisconnect)
+		defer close(release)
+		conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+		writeCommand(t, conn, cmdSlow, nil)
+		<-started
+		writeCommand(t, conn, cmdSlow, nil)
+		writeCommand(t, conn, cmdSlow, nil)
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+			t.Errorf("ReadMessage() error = %v, want close %d", err, websocket.CloseTryAgainLater)
+		}
+	})
+}
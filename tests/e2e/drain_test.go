@@ -0,0 +1,158 @@
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdWork uint32 = 0x00B0
+
+func TestDrainWaitsForInFlightHandlers(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server.RegisterHandler(context.Background(), cmdWork, func(ctx context.Context, client knet.Client, payload []byte) {
+		close(started)
+		<-release
+		client.Send(ctx, cmdWork, []byte("done"))
+	})
+
+	url := serveOnRandomPort(t, server) + "/ws"
+	conn := dial(t, url)
+
+	writeCommand(t, conn, cmdWork, nil)
+	<-started
+
+	drained := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		drained <- server.Drain(ctx, "ws://elsewhere/ws")
+	}()
+
+	if cmd, payload := readCommand(t, conn); cmd != knet.CmdGoingAway || string(payload) != "ws://elsewhere/ws" {
+		t.Fatalf("first frame = (0x%x, %q), want CmdGoingAway with hint", cmd, payload)
+	}
+
+	t.Run("refuses new connections", func(t *testing.T) {
+		_, resp, err := newDialer().Dial(url, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Dial() = (%v, %v), want 503", resp, err)
+		}
+	})
+
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain() returned %v before the handler finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	if cmd, payload := readCommand(t, conn); cmd != cmdWork || string(payload) != "done" {
+		t.Errorf("reply = (0x%x, %q), want handler reply before close", cmd, payload)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) || !strings.Contains(err.Error(), knet.ErrServerDraining) {
+		t.Errorf("ReadMessage() error = %v, want close %d", err, websocket.CloseGoingAway)
+	}
+	if err := <-drained; err != nil {
+		t.Errorf("Drain() error = %v", err)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+
+	started := make(chan struct{})
+	server.RegisterHandler(context.Background(), cmdWork, func(ctx context.Context, client knet.Client, payload []byte) {
+		close(started)
+		<-ctx.Done()
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	writeCommand(t, conn, cmdWork, nil)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := server.Drain(ctx, ""); err != context.DeadlineExceeded {
+		t.Errorf("Drain() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDrainNotifiesPastSlowClients(t *testing.T) {
+	t.Parallel()
+
+	const slowClients, fastClients = 2, 3
+	connected := make(chan knet.Client, slowClients+fastClients)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil)
+	cfg.SendQueue = &ws.SendQueueConfig{Size: 2, Policy: ws.SendQueueBlock}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	// Slow clients never read; fill their queues until Send would block
+	payload := make([]byte, 256*1024)
+	for i := 0; i < slowClients; i++ {
+		dial(t, url)
+		slow := <-connected
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			err := slow.Send(ctx, cmdFlood, payload)
+			cancel()
+			if err == context.DeadlineExceeded {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+		}
+	}
+
+	notified := make(chan time.Time, fastClients)
+	for i := 0; i < fastClients; i++ {
+		conn := dial(t, url)
+		<-connected
+		go func() {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, data, err := conn.ReadMessage()
+			if cmd, payload, _ := protocol.Decode(data); err != nil || cmd != knet.CmdGoingAway || string(payload) != "bye" {
+				t.Errorf("got (0x%x, %q, %v), want CmdGoingAway", cmd, payload, err)
+			}
+			notified <- time.Now()
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := server.Drain(ctx, "bye"); err != context.DeadlineExceeded {
+		t.Errorf("Drain() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Slow clients are closed in parallel, each taking up to a second
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Drain() took %s, want bounded by ctx and one close timeout", elapsed)
+	}
+
+	for i := 0; i < fastClients; i++ {
+		if after := (<-notified).Sub(start); after > 200*time.Millisecond {
+			t.Errorf("going-away notice received after %s, want without waiting on slow clients", after)
+		}
+	}
+}
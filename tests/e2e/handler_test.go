@@ -0,0 +1,81 @@
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdEchoHandler uint32 = 0x0010
+
+func newEchoServer(path string) knet.WebsocketServer {
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.Path = path
+
+	server := ws.New(cfg)
+	server.RegisterHandler(context.Background(), cmdEchoHandler, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(context.Background(), cmdEchoHandler, payload)
+	})
+
+	return server
+}
+
+func TestHandlerMountedInMux(t *testing.T) {
+	t.Parallel()
+
+	server := newEchoServer("")
+
+	mux := http.NewServeMux()
+	mux.Handle("/realtime", server.Handler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+	defer server.Stop(context.Background())
+
+	conn := dial(t, "ws"+strings.TrimPrefix(httpServer.URL, "http")+"/realtime")
+
+	writeCommand(t, conn, cmdEchoHandler, []byte("mounted"))
+	commandID, payload := readCommand(t, conn)
+
+	if commandID != cmdEchoHandler || string(payload) != "mounted" {
+		t.Errorf("got (0x%x, %q), want (0x%x, %q)", commandID, payload, cmdEchoHandler, "mounted")
+	}
+
+	resp, err := http.Get(httpServer.URL + "/health")
+	if err != nil {
+		t.Fatalf("health request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("health status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestServeWithCustomPath(t *testing.T) {
+	t.Parallel()
+
+	server := newEchoServer("/custom")
+	baseURL := serveOnRandomPort(t, server)
+
+	if _, _, err := newDialer().Dial(baseURL+"/ws", nil); err == nil {
+		t.Error("expected dial on default path to fail when a custom path is configured")
+	}
+
+	conn := dial(t, baseURL+"/custom")
+
+	writeCommand(t, conn, cmdEchoHandler, []byte("served"))
+	_, payload := readCommand(t, conn)
+
+	if string(payload) != "served" {
+		t.Errorf("got %q, want %q", payload, "served")
+	}
+}
@@ -0,0 +1,62 @@
+package e2e_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+func TestClientHandshake(t *testing.T) {
+	t.Parallel()
+
+	handshakes := make(chan *knet.Handshake, 1)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		handshakes <- client.Handshake()
+	}, nil)
+	cfg.Subprotocols = []string{"kephas.v2", "kephas.v1"}
+	server := ws.New(cfg)
+	url := serveOnRandomPort(t, server)
+
+	header := http.Header{}
+	header.Set("X-Request-ID", "req-42")
+	header.Set("Cookie", "session=s3cr3t")
+	dialer := newDialer()
+	dialer.Subprotocols = []string{"kephas.v1"}
+	conn, _, err := dialer.Dial(url+"/ws?token=abc", header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	h := <-handshakes
+	if got := h.HeaderValue("X-Request-ID"); got != "req-42" {
+		t.Errorf("X-Request-ID = %q, want %q", got, "req-42")
+	}
+	if got := h.Query().Get("token"); got != "abc" {
+		t.Errorf("token = %q, want %q", got, "abc")
+	}
+	if got := h.Path(); got != "/ws" {
+		t.Errorf("Path() = %q, want /ws", got)
+	}
+	if cookie, err := h.Cookie("session"); err != nil || cookie.Value != "s3cr3t" {
+		t.Errorf("Cookie(session) = %v, %v, want s3cr3t", cookie, err)
+	}
+	if _, err := h.Cookie("missing"); err != http.ErrNoCookie {
+		t.Errorf("Cookie(missing) error = %v, want http.ErrNoCookie", err)
+	}
+	if got := h.Subprotocol(); got != "kephas.v1" {
+		t.Errorf("Subprotocol() = %q, want kephas.v1", got)
+	}
+	if h.TLS() != nil {
+		t.Error("TLS() should be nil for a plain connection")
+	}
+
+	// The snapshot cannot be changed through its accessors
+	h.Header().Set("X-Request-ID", "changed")
+	h.URL().RawQuery = ""
+	if h.HeaderValue("X-Request-ID") != "req-42" || h.Query().Get("token") != "abc" {
+		t.Error("modifying returned copies changed the handshake")
+	}
+}
@@ -1,9 +1,14 @@
 package e2e_test
 
 import (
+	"context"
+	"net"
+	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
 )
 
 // Helper function to create a WebSocket dialer
@@ -12,3 +17,69 @@ func newDialer() *websocket.Dialer {
 		HandshakeTimeout: 5 * time.Second,
 	}
 }
+
+// serveOnRandomPort serves the server on a loopback listener and returns the
+// base WebSocket URL (without path). The server is stopped when the test ends.
+func serveOnRandomPort(t *testing.T, server knet.WebsocketServer) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go server.Serve(ln)
+
+	t.Cleanup(func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Stop(stopCtx)
+	})
+
+	return "ws://" + ln.Addr().String()
+}
+
+// dial connects to the given URL and closes the connection when the test ends
+func dial(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := newDialer().Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// writeCommand encodes and writes a protocol message
+func writeCommand(t *testing.T, conn *websocket.Conn, commandID uint32, payload []byte) {
+	t.Helper()
+
+	encoded, err := protocol.Encode(commandID, payload)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, encoded); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+}
+
+// readCommand reads and decodes the next protocol message
+func readCommand(t *testing.T, conn *websocket.Conn) (uint32, []byte) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+
+	commandID, payload, err := protocol.Decode(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	return commandID, payload
+}
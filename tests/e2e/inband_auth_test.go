@@ -0,0 +1,123 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdLogin   uint32 = 0x00E0
+	cmdProfile uint32 = 0x00E1
+)
+
+// newInBandAuthServer accepts the token "secret" through cmdLogin or the
+// "auth.login" JSON-RPC method, and echoes the principal on cmdProfile
+func newInBandAuthServer(t *testing.T, timeout time.Duration) string {
+	t.Helper()
+
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.InBandAuth = &ws.InBandAuthConfig{
+		CommandID: cmdLogin,
+		Method:    "auth.login",
+		Timeout:   timeout,
+		Validate: func(ctx context.Context, client knet.Client, credentials []byte) (any, error) {
+			var token string
+			if err := json.Unmarshal(credentials, &token); err != nil || token != "secret" {
+				return nil, errors.New("bad token")
+			}
+			return "alice", nil
+		},
+	}
+	server := ws.New(cfg)
+	server.RegisterHandler(context.Background(), cmdProfile, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdProfile, []byte(client.Principal().(string)))
+	})
+
+	return serveOnRandomPort(t, server) + "/ws"
+}
+
+// expectClose reads until the connection is closed and checks the close code
+func expectClose(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, code) {
+				t.Errorf("ReadMessage() error = %v, want close %d", err, code)
+			}
+			return
+		}
+	}
+}
+
+func TestInBandAuth(t *testing.T) {
+	t.Parallel()
+
+	url := newInBandAuthServer(t, time.Second)
+
+	t.Run("command", func(t *testing.T) {
+		t.Parallel()
+
+		conn := dial(t, url)
+		writeCommand(t, conn, cmdLogin, []byte(`"secret"`))
+		if cmd, _ := readCommand(t, conn); cmd != cmdLogin {
+			t.Fatalf("command = 0x%x, want login ack", cmd)
+		}
+
+		writeCommand(t, conn, cmdProfile, nil)
+		if _, payload := readCommand(t, conn); string(payload) != "alice" {
+			t.Errorf("principal = %q, want alice", payload)
+		}
+	})
+
+	t.Run("json-rpc", func(t *testing.T) {
+		t.Parallel()
+
+		conn := dial(t, url)
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc":"2.0","method":"auth.login","params":"secret","id":1}`))
+		_, payload := readCommand(t, conn)
+
+		var resp struct {
+			Result bool `json:"result"`
+		}
+		if err := json.Unmarshal(payload, &resp); err != nil || !resp.Result {
+			t.Fatalf("response = %s, want result true", payload)
+		}
+
+		writeCommand(t, conn, cmdProfile, nil)
+		if _, payload := readCommand(t, conn); string(payload) != "alice" {
+			t.Errorf("principal = %q, want alice", payload)
+		}
+	})
+
+	t.Run("message before auth", func(t *testing.T) {
+		t.Parallel()
+
+		conn := dial(t, url)
+		writeCommand(t, conn, cmdProfile, nil)
+		expectClose(t, conn, websocket.ClosePolicyViolation)
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		t.Parallel()
+
+		conn := dial(t, url)
+		writeCommand(t, conn, cmdLogin, []byte(`"wrong"`))
+		expectClose(t, conn, websocket.ClosePolicyViolation)
+	})
+}
+
+func TestInBandAuthTimeout(t *testing.T) {
+	t.Parallel()
+
+	conn := dial(t, newInBandAuthServer(t, 100*time.Millisecond))
+	expectClose(t, conn, websocket.ClosePolicyViolation)
+}
@@ -0,0 +1,291 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+// newJSONRPCConformanceServer registers the methods used by the examples of
+// section 7 of the JSON-RPC 2.0 specification
+func newJSONRPCConformanceServer(t *testing.T, notified chan<- string) string {
+	t.Helper()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+	ctx := context.Background()
+	invalidParams := &ws.JSONRPCError{Code: knet.JSONRPCInvalidParams, Message: knet.ErrInvalidParams}
+
+	server.RegisterJSONRPCRawHandler(ctx, "subtract", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var positional []float64
+		if err := json.Unmarshal(params, &positional); err == nil {
+			if len(positional) != 2 {
+				return nil, invalidParams
+			}
+			return positional[0] - positional[1], nil
+		}
+		var named struct {
+			Minuend    *float64 `json:"minuend"`
+			Subtrahend *float64 `json:"subtrahend"`
+		}
+		if err := json.Unmarshal(params, &named); err != nil || named.Minuend == nil || named.Subtrahend == nil {
+			return nil, invalidParams
+		}
+		return *named.Minuend - *named.Subtrahend, nil
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "sum", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var values []float64
+		if err := json.Unmarshal(params, &values); err != nil {
+			return nil, invalidParams
+		}
+		var total float64
+		for _, v := range values {
+			total += v
+		}
+		return total, nil
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "get_data", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return []interface{}{"hello", 5}, nil
+	})
+	for _, method := range []string{"update", "notify_hello", "notify_sum"} {
+		method := method
+		server.RegisterJSONRPCRawHandler(ctx, method, func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			notified <- method
+			return nil, nil
+		})
+	}
+	server.RegisterJSONRPCHandler(ctx, "echo", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params, nil
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "withdraw", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, &ws.JSONRPCError{Code: -32010, Message: "Insufficient funds", Data: map[string]int{"balance": 3}}
+	})
+
+	return serveOnRandomPort(t, server) + "/ws"
+}
+
+// callJSONRPCRaw sends a raw JSON-RPC frame and returns the raw response
+func callJSONRPCRaw(t *testing.T, conn *websocket.Conn, request string) []byte {
+	t.Helper()
+
+	writeCommand(t, conn, knet.CmdJSONRPC, []byte(request))
+	cmd, body := readCommand(t, conn)
+	if cmd != knet.CmdJSONRPC {
+		t.Fatalf("response command = 0x%x, want CmdJSONRPC", cmd)
+	}
+	return body
+}
+
+// assertJSONEqual compares two JSON documents ignoring formatting
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("response %s is not JSON: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("expected %s is not JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("response = %s, want %s", got, want)
+	}
+}
+
+func TestJSONRPCConformance(t *testing.T) {
+	t.Parallel()
+
+	url := newJSONRPCConformanceServer(t, make(chan string, 16))
+
+	tests := []struct {
+		name     string
+		request  string
+		response string
+	}{
+		{
+			name:     "positional parameters",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": 1}`,
+			response: `{"jsonrpc": "2.0", "result": 19, "id": 1}`,
+		},
+		{
+			name:     "positional parameters reversed",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": [23, 42], "id": 2}`,
+			response: `{"jsonrpc": "2.0", "result": -19, "id": 2}`,
+		},
+		{
+			name:     "named parameters",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": {"subtrahend": 23, "minuend": 42}, "id": 3}`,
+			response: `{"jsonrpc": "2.0", "result": 19, "id": 3}`,
+		},
+		{
+			name:     "named parameters reordered",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": {"minuend": 42, "subtrahend": 23}, "id": 4}`,
+			response: `{"jsonrpc": "2.0", "result": 19, "id": 4}`,
+		},
+		{
+			name:     "string id",
+			request:  `{"jsonrpc": "2.0", "method": "sum", "params": [1, 2, 4], "id": "abc"}`,
+			response: `{"jsonrpc": "2.0", "result": 7, "id": "abc"}`,
+		},
+		{
+			name:     "null id is a request",
+			request:  `{"jsonrpc": "2.0", "method": "get_data", "id": null}`,
+			response: `{"jsonrpc": "2.0", "result": ["hello", 5], "id": null}`,
+		},
+		{
+			name:     "null result",
+			request:  `{"jsonrpc": "2.0", "method": "update", "params": [1], "id": 5}`,
+			response: `{"jsonrpc": "2.0", "result": null, "id": 5}`,
+		},
+		{
+			name:     "non-existent method",
+			request:  `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": "1"}`,
+		},
+		{
+			name:     "invalid JSON",
+			request:  `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`,
+		},
+		{
+			name:     "invalid request object",
+			request:  `{"jsonrpc": "2.0", "method": 1, "params": "bar"}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+		},
+		{
+			name:     "wrong version",
+			request:  `{"jsonrpc": "1.0", "method": "sum", "params": [1], "id": 6}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": 6}`,
+		},
+		{
+			name:     "scalar params",
+			request:  `{"jsonrpc": "2.0", "method": "sum", "params": 5, "id": 7}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": 7}`,
+		},
+		{
+			name:     "object id",
+			request:  `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": {"a": 1}}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+		},
+		{
+			name:     "invalid params",
+			request:  `{"jsonrpc": "2.0", "method": "subtract", "params": [1, 2, 3], "id": 8}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params"}, "id": 8}`,
+		},
+		{
+			name:     "positional params to a named-params handler",
+			request:  `{"jsonrpc": "2.0", "method": "echo", "params": [1, 2], "id": 9}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params"}, "id": 9}`,
+		},
+		{
+			name:     "error data",
+			request:  `{"jsonrpc": "2.0", "method": "withdraw", "params": {"amount": 10}, "id": 10}`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32010, "message": "Insufficient funds", "data": {"balance": 3}}, "id": 10}`,
+		},
+		{
+			name:     "batch with invalid JSON",
+			request:  `[{"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"}, {"jsonrpc": "2.0", "method"]`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`,
+		},
+		{
+			name:     "empty batch",
+			request:  `[]`,
+			response: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+		},
+		{
+			name:     "invalid batch",
+			request:  `[1]`,
+			response: `[{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}]`,
+		},
+		{
+			name:    "invalid batch entries",
+			request: `[1,2,3]`,
+			response: `[
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}
+			]`,
+		},
+		{
+			name: "mixed batch",
+			request: `[
+				{"jsonrpc": "2.0", "method": "sum", "params": [1,2,4], "id": "1"},
+				{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]},
+				{"jsonrpc": "2.0", "method": "subtract", "params": [42,23], "id": "2"},
+				{"foo": "boo"},
+				{"jsonrpc": "2.0", "method": "foo.get", "params": {"name": "myself"}, "id": "5"},
+				{"jsonrpc": "2.0", "method": "get_data", "id": "9"}
+			]`,
+			response: `[
+				{"jsonrpc": "2.0", "result": 7, "id": "1"},
+				{"jsonrpc": "2.0", "result": 19, "id": "2"},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": "5"},
+				{"jsonrpc": "2.0", "result": ["hello", 5], "id": "9"}
+			]`,
+		},
+	}
+
+	conn := dial(t, url)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSONEqual(t, callJSONRPCRaw(t, conn, tt.request), tt.response)
+		})
+	}
+}
+
+func TestJSONRPCNotifications(t *testing.T) {
+	t.Parallel()
+
+	notified := make(chan string, 16)
+	url := newJSONRPCConformanceServer(t, notified)
+	conn := dial(t, url)
+
+	expectNotified := func(t *testing.T, want ...string) {
+		t.Helper()
+		got := make(map[string]int)
+		for range want {
+			select {
+			case method := <-notified:
+				got[method]++
+			case <-time.After(5 * time.Second):
+				t.Fatalf("notifications received = %v, want %v", got, want)
+			}
+		}
+		for _, method := range want {
+			got[method]--
+		}
+		for method, n := range got {
+			if n != 0 {
+				t.Errorf("notification %q handled %+d times more than expected", method, n)
+			}
+		}
+	}
+
+	// Notifications are never answered, so the next frame read belongs to the
+	// request sent after them
+	t.Run("single", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc": "2.0", "method": "update", "params": [1,2,3,4,5]}`))
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc": "2.0", "method": "foobar"}`))
+		expectNotified(t, "update")
+
+		assertJSONEqual(t, callJSONRPCRaw(t, conn, `{"jsonrpc": "2.0", "method": "sum", "params": [1], "id": 1}`),
+			`{"jsonrpc": "2.0", "result": 1, "id": 1}`)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`[
+			{"jsonrpc": "2.0", "method": "notify_sum", "params": [1,2,4]},
+			{"jsonrpc": "2.0", "method": "notify_hello", "params": [7]}
+		]`))
+		expectNotified(t, "notify_sum", "notify_hello")
+
+		assertJSONEqual(t, callJSONRPCRaw(t, conn, `{"jsonrpc": "2.0", "method": "sum", "params": [2], "id": 2}`),
+			`{"jsonrpc": "2.0", "result": 2, "id": 2}`)
+	})
+}
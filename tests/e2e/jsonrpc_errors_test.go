@@ -0,0 +1,118 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+var errAccountLocked = errors.New("account locked")
+
+func TestJSONRPCStructuredErrors(t *testing.T) {
+	t.Parallel()
+
+	handlerErrors := make(chan *knet.HandlerError, 4)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.OnHandlerError = func(err *knet.HandlerError) {
+		handlerErrors <- err
+	}
+	cfg.JSONRPCErrorMapper = func(client knet.Client, method string, err error) *knet.RPCError {
+		if errors.Is(err, errAccountLocked) {
+			return &knet.RPCError{Code: 1002, Message: "account locked", Data: method}
+		}
+		return nil
+	}
+	server := ws.New(cfg)
+	ctx := context.Background()
+
+	server.RegisterJSONRPCRawHandler(ctx, "withdraw", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, &knet.RPCError{Code: 1001, Message: "insufficient funds", Data: map[string]int{"balance": 3}}
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "transfer", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("transfer: %w", &knet.RPCError{Code: 1003, Message: "unknown recipient"})
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "login", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("login: %w", errAccountLocked)
+	})
+	server.RegisterJSONRPCRawHandler(ctx, "balance", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, errors.New("pq: connection refused to 10.0.0.5:5432")
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+	tests := []struct {
+		name     string
+		method   string
+		response string
+	}{
+		{
+			name:     "custom code and data",
+			method:   "withdraw",
+			response: `{"jsonrpc":"2.0","error":{"code":1001,"message":"insufficient funds","data":{"balance":3}},"id":1}`,
+		},
+		{
+			name:     "wrapped error",
+			method:   "transfer",
+			response: `{"jsonrpc":"2.0","error":{"code":1003,"message":"unknown recipient"},"id":1}`,
+		},
+		{
+			name:     "mapped error",
+			method:   "login",
+			response: `{"jsonrpc":"2.0","error":{"code":1002,"message":"account locked","data":"login"},"id":1}`,
+		},
+		{
+			name:     "unknown error is sanitized",
+			method:   "balance",
+			response: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"`+tt.method+`","id":1}`)
+			assertJSONEqual(t, body, tt.response)
+		})
+	}
+
+	// Only the sanitized error is reported, so its text is not lost
+	select {
+	case err := <-handlerErrors:
+		if err.Method != "balance" || err.Err == nil || err.Err.Error() != "pq: connection refused to 10.0.0.5:5432" {
+			t.Errorf("handler error = %v, want the balance error", err)
+		}
+	default:
+		t.Error("sanitized error was not reported to OnHandlerError")
+	}
+	if len(handlerErrors) != 0 {
+		t.Errorf("%d unexpected handler errors reported", len(handlerErrors))
+	}
+}
+
+func TestJSONRPCPanicSkipsErrorMapper(t *testing.T) {
+	t.Parallel()
+
+	var mapped atomic.Int32
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.JSONRPCErrorMapper = func(client knet.Client, method string, err error) *knet.RPCError {
+		mapped.Add(1)
+		return &knet.RPCError{Code: 1000, Message: "application error"}
+	}
+	server := ws.New(cfg)
+
+	server.RegisterJSONRPCRawHandler(context.Background(), "crash", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		panic("boom")
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	body := callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"crash","id":1}`)
+	assertJSONEqual(t, body, `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`)
+	if n := mapped.Load(); n != 0 {
+		t.Errorf("error mapper called %d times for a panic, want 0", n)
+	}
+}
@@ -0,0 +1,181 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+	"github.com/luciancaetano/knet/wsclient"
+)
+
+func TestServerJSONRPCToGoClient(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 1)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	goClient, err := wsclient.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer goClient.Close()
+
+	notifications := make(chan json.RawMessage, 1)
+	goClient.OnJSONRPC("chat.message", func(params json.RawMessage) (any, error) {
+		notifications <- params
+		return nil, nil
+	})
+	goClient.OnJSONRPC("ui.confirm", func(params json.RawMessage) (any, error) {
+		var p struct {
+			Text string `json:"text"`
+		}
+		json.Unmarshal(params, &p)
+		return p.Text == "Delete?", nil
+	})
+	goClient.OnJSONRPC("ui.prompt", func(params json.RawMessage) (any, error) {
+		return nil, &knet.RPCError{Code: 1001, Message: "dismissed", Data: "closed by user"}
+	})
+
+	client := <-connected
+
+	t.Run("notify", func(t *testing.T) {
+		if err := client.NotifyJSONRPC(ctx, "chat.message", map[string]string{"text": "hi"}); err != nil {
+			t.Fatalf("NotifyJSONRPC() error = %v", err)
+		}
+		select {
+		case params := <-notifications:
+			if string(params) != `{"text":"hi"}` {
+				t.Errorf("params = %s, want {\"text\":\"hi\"}", params)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification not received")
+		}
+	})
+
+	t.Run("call", func(t *testing.T) {
+		var confirmed bool
+		if err := client.CallJSONRPC(ctx, "ui.confirm", map[string]string{"text": "Delete?"}, &confirmed); err != nil || !confirmed {
+			t.Errorf("CallJSONRPC() = (%v, %v), want (true, nil)", confirmed, err)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		var rpcErr *knet.RPCError
+		err := client.CallJSONRPC(ctx, "ui.prompt", []string{"Name?"}, nil)
+		if !errors.As(err, &rpcErr) || rpcErr.Code != 1001 || rpcErr.Message != "dismissed" || rpcErr.Data != "closed by user" {
+			t.Errorf("CallJSONRPC() error = %#v, want the client's error", err)
+		}
+
+		err = client.CallJSONRPC(ctx, "ui.missing", nil, nil)
+		if !errors.As(err, &rpcErr) || rpcErr.Code != knet.JSONRPCMethodNotFound {
+			t.Errorf("CallJSONRPC() error = %v, want method not found", err)
+		}
+	})
+
+	t.Run("scalar params", func(t *testing.T) {
+		if err := client.NotifyJSONRPC(ctx, "chat.message", "hi"); err == nil || !strings.Contains(err.Error(), knet.ErrInvalidParams) {
+			t.Errorf("NotifyJSONRPC() error = %v, want invalid params", err)
+		}
+	})
+}
+
+func TestServerJSONRPCCallWire(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 1)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+	client := <-connected
+
+	type outcome struct {
+		result json.RawMessage
+		err    error
+	}
+	call := func(ctx context.Context) <-chan outcome {
+		done := make(chan outcome, 1)
+		go func() {
+			var result json.RawMessage
+			err := client.CallJSONRPC(ctx, "clock.now", nil, &result)
+			done <- outcome{result, err}
+		}()
+		return done
+	}
+
+	t.Run("response routing", func(t *testing.T) {
+		done := call(context.Background())
+
+		cmd, body := readCommand(t, conn)
+		var req struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params"`
+			ID      json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || cmd != knet.CmdJSONRPC {
+			t.Fatalf("request = (0x%x, %s), want a JSON-RPC request", cmd, body)
+		}
+		if req.JSONRPC != knet.JSONRPCVersion || req.Method != "clock.now" || req.Params != nil || req.ID == nil {
+			t.Fatalf("request = %s, want clock.now with an id and no params", body)
+		}
+
+		// A response to an unknown id is dropped without a reply, and a binary
+		// response with the same id does not resolve the JSON-RPC call
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc":"2.0","result":1,"id":999999}`))
+		id, err := strconv.ParseUint(string(req.ID), 10, 32)
+		if err != nil {
+			t.Fatalf("id = %s, want a number", req.ID)
+		}
+		writeCommand(t, conn, knet.CmdResponse, protocol.EncodeResponse(uint32(id), []byte("wrong kind")))
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc":"2.0","result":"12:00","id":`+string(req.ID)+`}`))
+
+		select {
+		case got := <-done:
+			if got.err != nil || string(got.result) != `"12:00"` {
+				t.Errorf("CallJSONRPC() = (%s, %v), want \"12:00\"", got.result, got.err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("CallJSONRPC() did not return")
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		done := call(ctx)
+		readCommand(t, conn)
+
+		if got := <-done; !errors.Is(got.err, context.DeadlineExceeded) {
+			t.Errorf("CallJSONRPC() error = %v, want deadline exceeded", got.err)
+		}
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		done := call(context.Background())
+		readCommand(t, conn)
+		conn.Close()
+
+		select {
+		case got := <-done:
+			if got.err == nil {
+				t.Error("CallJSONRPC() error = nil, want connection closed")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("CallJSONRPC() did not return after disconnect")
+		}
+	})
+}
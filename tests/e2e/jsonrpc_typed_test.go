@@ -0,0 +1,74 @@
+package e2e_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+type moveParams struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type moveResult struct {
+	ClientID string `json:"clientId"`
+	Distance int    `json:"distance"`
+}
+
+func TestRegisterJSONRPCTyped(t *testing.T) {
+	t.Parallel()
+
+	connected := make(chan knet.Client, 1)
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), func(client knet.Client) {
+		connected <- client
+	}, nil))
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	knet.RegisterJSONRPC(ctx, server, "player.move", func(ctx context.Context, client knet.Client, p moveParams) (moveResult, error) {
+		calls.Add(1)
+		return moveResult{ClientID: client.ID(), Distance: p.X + p.Y}, nil
+	})
+	knet.RegisterJSONRPC(ctx, server, "math.max", func(ctx context.Context, client knet.Client, values []int) (int, error) {
+		largest := 0
+		for _, v := range values {
+			if v > largest {
+				largest = v
+			}
+		}
+		return largest, nil
+	})
+
+	url := serveOnRandomPort(t, server) + "/ws"
+	conn := dial(t, url)
+	client := <-connected
+
+	t.Run("named params", func(t *testing.T) {
+		assertJSONEqual(t, callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"player.move","params":{"x":3,"y":4},"id":1}`),
+			`{"jsonrpc":"2.0","result":{"clientId":"`+client.ID()+`","distance":7},"id":1}`)
+	})
+
+	t.Run("positional params", func(t *testing.T) {
+		assertJSONEqual(t, callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"math.max","params":[4,9,2],"id":2}`),
+			`{"jsonrpc":"2.0","result":9,"id":2}`)
+	})
+
+	t.Run("omitted params", func(t *testing.T) {
+		assertJSONEqual(t, callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"math.max","id":3}`),
+			`{"jsonrpc":"2.0","result":0,"id":3}`)
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		before := calls.Load()
+		body := callJSONRPCRaw(t, conn, `{"jsonrpc":"2.0","method":"player.move","params":{"x":"far"},"id":4}`)
+
+		assertJSONEqual(t, body, `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":{"field":"x"}},"id":4}`)
+		if calls.Load() != before {
+			t.Error("handler was called with undecodable params")
+		}
+	})
+}
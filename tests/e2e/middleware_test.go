@@ -0,0 +1,121 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdMWEcho  uint32 = 0x0070
+	cmdMWAdmin uint32 = 0x0071
+)
+
+var errForbidden = errors.New("forbidden")
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var seen []knet.Message
+	server.Use(func(next knet.Handler) knet.Handler {
+		return func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+			mu.Lock()
+			seen = append(seen, *msg)
+			mu.Unlock()
+			return next(ctx, client, msg)
+		}
+	})
+
+	requireAdmin := func(next knet.Handler) knet.Handler {
+		return func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+			if string(msg.Payload) != "admin" {
+				return nil, errForbidden
+			}
+			return next(ctx, client, msg)
+		}
+	}
+
+	server.RegisterHandler(ctx, cmdMWEcho, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdMWEcho, payload)
+	})
+	server.RegisterRequestHandler(ctx, cmdMWAdmin, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		return []byte("ok"), nil
+	}, knet.WithMiddleware(requireAdmin))
+	server.RegisterJSONRPCHandler(ctx, "double", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["n"].(float64) * 2, nil
+	}, knet.WithMiddleware(func(next knet.Handler) knet.Handler {
+		return func(ctx context.Context, client knet.Client, msg *knet.Message) (any, error) {
+			if msg.Method != "double" {
+				t.Errorf("msg.Method = %q, want %q", msg.Method, "double")
+			}
+			return next(ctx, client, msg)
+		}
+	}))
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+	t.Run("global middleware wraps commands", func(t *testing.T) {
+		writeCommand(t, conn, cmdMWEcho, []byte("ping"))
+
+		if cmd, payload := readCommand(t, conn); cmd != cmdMWEcho || string(payload) != "ping" {
+			t.Errorf("reply = (0x%x, %q), want (0x%x, %q)", cmd, payload, cmdMWEcho, "ping")
+		}
+	})
+
+	t.Run("per-command middleware short-circuits", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdMWAdmin, 1, []byte("guest")))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		if _, _, message, _ := protocol.DecodeError(body); message != errForbidden.Error() {
+			t.Errorf("message = %q, want %q", message, errForbidden.Error())
+		}
+
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdMWAdmin, 2, []byte("admin")))
+
+		if cmd, _ := readCommand(t, conn); cmd != knet.CmdResponse {
+			t.Errorf("command = 0x%x, want CmdResponse", cmd)
+		}
+	})
+
+	t.Run("JSON-RPC methods go through the chain", func(t *testing.T) {
+		request, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": knet.JSONRPCVersion,
+			"method":  "double",
+			"params":  map[string]int{"n": 21},
+			"id":      1,
+		})
+		writeCommand(t, conn, knet.CmdJSONRPC, request)
+
+		_, body := readCommand(t, conn)
+		var response struct {
+			Result float64 `json:"result"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil || response.Result != 42 {
+			t.Errorf("response = %s, want result 42", body)
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	kinds := make(map[knet.MessageKind]int)
+	for _, msg := range seen {
+		kinds[msg.Kind]++
+	}
+	if kinds[knet.KindCommand] != 1 || kinds[knet.KindRequest] != 2 || kinds[knet.KindJSONRPC] != 1 {
+		t.Errorf("global middleware saw %v, want 1 command, 2 requests and 1 JSON-RPC call", kinds)
+	}
+}
@@ -0,0 +1,94 @@
+package e2e_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdOrderEvent uint32 = 0x0040
+
+func TestPublishToMatchingSubscribers(t *testing.T) {
+	t.Parallel()
+
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.AuthorizeSubscribe = func(client knet.Client, pattern string) bool {
+		return !strings.HasPrefix(pattern, "admin.")
+	}
+	server := ws.New(cfg)
+	baseURL := serveOnRandomPort(t, server)
+
+	eu := dial(t, baseURL+"/ws")
+	all := dial(t, baseURL+"/ws")
+
+	writeCommand(t, eu, knet.CmdSubscribe, []byte("orders.eu.*"))
+	if cmd, payload := readCommand(t, eu); cmd != knet.CmdSubscribe || string(payload) != "orders.eu.*" {
+		t.Fatalf("subscribe ack = (0x%x, %q), want (0x%x, %q)", cmd, payload, knet.CmdSubscribe, "orders.eu.*")
+	}
+
+	writeCommand(t, all, knet.CmdSubscribe, []byte("orders.>"))
+	if cmd, _ := readCommand(t, all); cmd != knet.CmdSubscribe {
+		t.Fatalf("subscribe ack command = 0x%x, want 0x%x", cmd, knet.CmdSubscribe)
+	}
+
+	// Denied by the authorization hook
+	writeCommand(t, all, knet.CmdSubscribe, []byte("admin.audit"))
+	if cmd, payload := readCommand(t, all); cmd != knet.CmdSubscribeDenied || string(payload) != "admin.audit" {
+		t.Errorf("denied subscribe = (0x%x, %q), want (0x%x, %q)", cmd, payload, knet.CmdSubscribeDenied, "admin.audit")
+	}
+
+	// Invalid pattern
+	writeCommand(t, all, knet.CmdSubscribe, []byte("orders.>.eu"))
+	if cmd, _ := readCommand(t, all); cmd != knet.CmdSubscribeDenied {
+		t.Errorf("invalid pattern answered with 0x%x, want 0x%x", cmd, knet.CmdSubscribeDenied)
+	}
+
+	ctx := context.Background()
+	if report, err := server.Publish(ctx, "orders.us.created", cmdOrderEvent, []byte("us-1")); err != nil || report.Delivered != 1 {
+		t.Fatalf("Publish() = %+v, %v, want 1 delivered", report, err)
+	}
+	if report, err := server.Publish(ctx, "orders.eu.created", cmdOrderEvent, []byte("eu-1")); err != nil || report.Delivered != 2 {
+		t.Fatalf("Publish() = %+v, %v, want 2 delivered", report, err)
+	}
+
+	if _, payload := readCommand(t, all); string(payload) != "us-1" {
+		t.Errorf("wildcard subscriber got %q, want %q", payload, "us-1")
+	}
+	if _, payload := readCommand(t, all); string(payload) != "eu-1" {
+		t.Errorf("wildcard subscriber got %q, want %q", payload, "eu-1")
+	}
+	if _, payload := readCommand(t, eu); string(payload) != "eu-1" {
+		t.Errorf("eu subscriber got %q, want %q", payload, "eu-1")
+	}
+
+	// Unsubscribe stops delivery
+	writeCommand(t, eu, knet.CmdUnsubscribe, []byte("orders.eu.*"))
+	if cmd, _ := readCommand(t, eu); cmd != knet.CmdUnsubscribe {
+		t.Fatalf("unsubscribe ack command = 0x%x, want 0x%x", cmd, knet.CmdUnsubscribe)
+	}
+
+	server.Publish(ctx, "orders.eu.updated", cmdOrderEvent, []byte("eu-2"))
+	eu.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := eu.ReadMessage(); err == nil {
+		t.Error("unsubscribed client still received a publication")
+	}
+
+	if _, err := server.Publish(ctx, "orders.*", cmdOrderEvent, nil); err == nil {
+		t.Error("expected Publish() with a wildcard topic to fail")
+	}
+}
+
+func TestRegisterReservedCommandFails(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+
+	err := server.RegisterHandler(context.Background(), knet.CmdSubscribe, func(ctx context.Context, client knet.Client, payload []byte) {})
+	if err == nil {
+		t.Error("expected RegisterHandler() with a reserved command ID to fail")
+	}
+}
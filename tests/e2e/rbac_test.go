@@ -0,0 +1,110 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdAdminOnly   uint32 = 0x00F0
+	cmdAdminLookup uint32 = 0x00F1
+)
+
+func TestRequirePermissions(t *testing.T) {
+	t.Parallel()
+
+	denials := make(chan *knet.PermissionError, 8)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.Authenticate = func(r *http.Request) (any, error) {
+		roles := r.URL.Query().Get("roles")
+		return knet.Permissions(strings.Split(roles, ",")), nil
+	}
+	cfg.OnPermissionDenied = func(err *knet.PermissionError) {
+		denials <- err
+	}
+	server := ws.New(cfg)
+	ctx := context.Background()
+
+	admin := knet.RequirePermissions("role:admin")
+	server.RegisterHandler(ctx, cmdAdminOnly, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdAdminOnly, []byte("done"))
+	}, admin)
+	server.RegisterRequestHandler(ctx, cmdAdminLookup, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		return []byte("secret"), nil
+	}, admin)
+	server.RegisterJSONRPCHandler(ctx, "closeTicket", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "closed", nil
+	}, knet.RequirePermissions("role:support"))
+
+	url := serveOnRandomPort(t, server) + "/ws"
+
+	t.Run("allowed", func(t *testing.T) {
+		conn := dial(t, url+"?roles=role:admin")
+		writeCommand(t, conn, cmdAdminOnly, nil)
+		if cmd, payload := readCommand(t, conn); cmd != cmdAdminOnly || string(payload) != "done" {
+			t.Errorf("got (0x%x, %q), want handler reply", cmd, payload)
+		}
+	})
+
+	conn := dial(t, url+"?roles=role:player")
+
+	t.Run("command denied", func(t *testing.T) {
+		writeCommand(t, conn, cmdAdminOnly, nil)
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdPermissionDenied {
+			t.Fatalf("command = 0x%x, want CmdPermissionDenied", cmd)
+		}
+		commandID, permission, err := protocol.DecodeDenied(body)
+		if err != nil || commandID != cmdAdminOnly || permission != "role:admin" {
+			t.Errorf("denial = (0x%x, %q, %v), want (0x%x, role:admin)", commandID, permission, err, cmdAdminOnly)
+		}
+
+		denied := <-denials
+		if denied.CommandID != cmdAdminOnly || denied.Permission != "role:admin" || denied.ClientID == "" {
+			t.Errorf("audit = %+v, want denial of 0x%x", denied, cmdAdminOnly)
+		}
+	})
+
+	t.Run("request denied", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdAdminLookup, 3, nil))
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		requestID, code, _, err := protocol.DecodeError(body)
+		if err != nil || requestID != 3 || code != knet.RequestErrPermissionDenied {
+			t.Errorf("error reply = (%d, %d, %v), want (3, %d)", requestID, code, err, knet.RequestErrPermissionDenied)
+		}
+		<-denials
+	})
+
+	t.Run("json-rpc denied", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdJSONRPC, []byte(`{"jsonrpc":"2.0","method":"closeTicket","params":{"id":1},"id":9}`))
+		_, body := readCommand(t, conn)
+
+		var resp struct {
+			Error struct {
+				Code int               `json:"code"`
+				Data map[string]string `json:"data"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", body, err)
+		}
+		if resp.Error.Code != knet.JSONRPCPermissionDenied || resp.Error.Data["permission"] != "role:support" {
+			t.Errorf("response = %s, want permission denied for role:support", body)
+		}
+
+		denied := <-denials
+		if denied.Method != "closeTicket" {
+			t.Errorf("audit method = %q, want closeTicket", denied.Method)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdPanic uint32 = 0x0080
+	cmdPing  uint32 = 0x0081
+)
+
+// newPanickingServer registers handlers that panic on every dispatch path
+func newPanickingServer(t *testing.T, policy ws.PanicPolicy) (knet.WebsocketServer, <-chan *knet.HandlerError) {
+	t.Helper()
+
+	reports := make(chan *knet.HandlerError, 8)
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.PanicPolicy = policy
+	cfg.OnHandlerError = func(err *knet.HandlerError) { reports <- err }
+	server := ws.New(cfg)
+
+	ctx := context.Background()
+	server.RegisterHandler(ctx, cmdPanic, func(ctx context.Context, client knet.Client, payload []byte) {
+		panic("boom")
+	})
+	server.RegisterHandler(ctx, cmdPing, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdPing, payload)
+	})
+	server.RegisterRequestHandler(ctx, cmdPanic, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		panic("boom")
+	})
+	server.RegisterJSONRPCHandler(ctx, "panic", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	return server, reports
+}
+
+func waitReport(t *testing.T, reports <-chan *knet.HandlerError) *knet.HandlerError {
+	t.Helper()
+
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnHandlerError was not called")
+		return nil
+	}
+}
+
+func TestHandlerPanicKeepsConnection(t *testing.T) {
+	t.Parallel()
+
+	server, reports := newPanickingServer(t, ws.PanicKeepConnection)
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+	t.Run("command panic is reported", func(t *testing.T) {
+		writeCommand(t, conn, cmdPanic, nil)
+
+		report := waitReport(t, reports)
+		if report.CommandID != cmdPanic || report.Panic != "boom" || len(report.Stack) == 0 || report.ClientID == "" {
+			t.Errorf("report = %+v, want panic of command 0x%x with stack", report, cmdPanic)
+		}
+
+		writeCommand(t, conn, cmdPing, []byte("still here"))
+		if cmd, payload := readCommand(t, conn); cmd != cmdPing || string(payload) != "still here" {
+			t.Errorf("reply = (0x%x, %q), want ping echo", cmd, payload)
+		}
+	})
+
+	t.Run("request panic answers internal error", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdPanic, 1, nil))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		if _, code, message, _ := protocol.DecodeError(body); code != knet.RequestErrInternal || message != knet.ErrInternalError {
+			t.Errorf("error = (%d, %q), want (%d, %q)", code, message, knet.RequestErrInternal, knet.ErrInternalError)
+		}
+		waitReport(t, reports)
+	})
+
+	t.Run("JSON-RPC panic answers internal error", func(t *testing.T) {
+		request, _ := json.Marshal(map[string]interface{}{"jsonrpc": knet.JSONRPCVersion, "method": "panic", "id": 1})
+		writeCommand(t, conn, knet.CmdJSONRPC, request)
+
+		_, body := readCommand(t, conn)
+		var response struct {
+			Error struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil || response.Error.Code != knet.JSONRPCInternalError {
+			t.Errorf("response = %s, want internal error", body)
+		}
+		if report := waitReport(t, reports); report.Method != "panic" || report.CommandID != knet.CmdJSONRPC {
+			t.Errorf("report = %+v, want method %q", report, "panic")
+		}
+	})
+}
+
+func TestHandlerPanicClosesConnection(t *testing.T) {
+	t.Parallel()
+
+	server, reports := newPanickingServer(t, ws.PanicCloseConnection)
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+	writeCommand(t, conn, cmdPanic, nil)
+	waitReport(t, reports)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+		t.Errorf("ReadMessage() error = %v, want close %d", err, websocket.CloseInternalServerErr)
+	}
+}
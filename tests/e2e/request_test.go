@@ -0,0 +1,86 @@
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdUpper   uint32 = 0x0050
+	cmdMissing uint32 = 0x0051
+)
+
+func TestCorrelatedRequests(t *testing.T) {
+	t.Parallel()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+	ctx := context.Background()
+
+	server.RegisterRequestHandler(ctx, cmdUpper, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		if len(payload) == 0 {
+			return nil, &knet.RequestError{Code: knet.RequestErrApplication, Message: "empty payload"}
+		}
+		return bytes.ToUpper(payload), nil
+	})
+	// Fire-and-forget handler on the same command ID
+	server.RegisterHandler(ctx, cmdUpper, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdUpper, []byte("fire-and-forget"))
+	})
+
+	conn := dial(t, serveOnRandomPort(t, server)+"/ws")
+
+	t.Run("reply carries request ID", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdUpper, 7, []byte("hello")))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponse {
+			t.Fatalf("command = 0x%x, want CmdResponse", cmd)
+		}
+		requestID, payload, err := protocol.DecodeResponse(body)
+		if err != nil {
+			t.Fatalf("DecodeResponse() error = %v", err)
+		}
+		if requestID != 7 || string(payload) != "HELLO" {
+			t.Errorf("response = (%d, %q), want (7, %q)", requestID, payload, "HELLO")
+		}
+	})
+
+	t.Run("typed handler error", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdUpper, 8, nil))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		requestID, code, message, _ := protocol.DecodeError(body)
+		if requestID != 8 || code != knet.RequestErrApplication || message != "empty payload" {
+			t.Errorf("error = (%d, %d, %q), want (8, %d, %q)", requestID, code, message, knet.RequestErrApplication, "empty payload")
+		}
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		writeCommand(t, conn, knet.CmdRequest, protocol.EncodeRequest(cmdMissing, 9, nil))
+
+		cmd, body := readCommand(t, conn)
+		if cmd != knet.CmdResponseError {
+			t.Fatalf("command = 0x%x, want CmdResponseError", cmd)
+		}
+		if _, code, _, _ := protocol.DecodeError(body); code != knet.RequestErrUnknownCommand {
+			t.Errorf("code = %d, want %d", code, knet.RequestErrUnknownCommand)
+		}
+	})
+
+	t.Run("plain frames still reach fire-and-forget handler", func(t *testing.T) {
+		writeCommand(t, conn, cmdUpper, []byte("hello"))
+
+		cmd, payload := readCommand(t, conn)
+		if cmd != cmdUpper || string(payload) != "fire-and-forget" {
+			t.Errorf("got (0x%x, %q), want (0x%x, %q)", cmd, payload, cmdUpper, "fire-and-forget")
+		}
+	})
+}
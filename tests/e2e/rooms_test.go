@@ -0,0 +1,93 @@
+package e2e_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdJoinRoom uint32 = 0x0030
+	cmdRoomChat uint32 = 0x0031
+)
+
+func TestBroadcastToRoom(t *testing.T) {
+	t.Parallel()
+
+	var server knet.WebsocketServer
+	roomsOnDisconnect := make(chan []string, 3)
+
+	server = ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, func(client knet.Client, voluntary bool) {
+		roomsOnDisconnect <- server.ClientRooms(client)
+	}))
+
+	ctx := context.Background()
+	server.RegisterHandler(ctx, cmdJoinRoom, func(ctx context.Context, client knet.Client, payload []byte) {
+		if err := server.Join(client, string(payload)); err != nil {
+			t.Errorf("Join() error = %v", err)
+		}
+		client.Send(ctx, cmdJoinRoom, payload)
+	})
+	server.RegisterHandler(ctx, cmdRoomChat, func(ctx context.Context, client knet.Client, payload []byte) {
+		for _, room := range server.ClientRooms(client) {
+			report, err := server.BroadcastToRoom(ctx, room, cmdRoomChat, payload, client.ID())
+			if err != nil || report.Delivered != 1 || report.Errors == nil {
+				t.Errorf("BroadcastToRoom() = %+v, %v, want 1 delivered and an error map", report, err)
+			}
+		}
+	})
+
+	baseURL := serveOnRandomPort(t, server)
+	sender := dial(t, baseURL+"/ws")
+	peer := dial(t, baseURL+"/ws")
+	outsider := dial(t, baseURL+"/ws")
+
+	writeCommand(t, sender, cmdJoinRoom, []byte("lobby"))
+	readCommand(t, sender)
+	writeCommand(t, peer, cmdJoinRoom, []byte("lobby"))
+	readCommand(t, peer)
+	writeCommand(t, outsider, cmdJoinRoom, []byte("other"))
+	readCommand(t, outsider)
+
+	if got := len(server.RoomMembers("lobby")); got != 2 {
+		t.Fatalf("len(RoomMembers(lobby)) = %d, want 2", got)
+	}
+
+	writeCommand(t, sender, cmdRoomChat, []byte("hi lobby"))
+
+	commandID, payload := readCommand(t, peer)
+	if commandID != cmdRoomChat || string(payload) != "hi lobby" {
+		t.Errorf("peer got (0x%x, %q), want (0x%x, %q)", commandID, payload, cmdRoomChat, "hi lobby")
+	}
+
+	// Neither the excluded sender nor the client in another room receive the message
+	for name, conn := range map[string]*websocket.Conn{"sender": sender, "outsider": outsider} {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Errorf("%s unexpectedly received a room message", name)
+		}
+	}
+
+	peer.Close()
+
+	select {
+	case rooms := <-roomsOnDisconnect:
+		if len(rooms) != 1 || rooms[0] != "lobby" {
+			t.Errorf("ClientRooms() during OnDisconnect = %v, want [lobby]", rooms)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.RoomMembers("lobby")) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(server.RoomMembers("lobby")); got != 1 {
+		t.Errorf("len(RoomMembers(lobby)) after disconnect = %d, want 1", got)
+	}
+}
@@ -0,0 +1,147 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const (
+	cmdChatSend     uint32 = 0x0100
+	cmdBalanceQuery uint32 = 0x0101
+)
+
+type chatMessage struct {
+	Room string `json:"room"`
+	Text string `json:"text"`
+}
+
+type balanceQuery struct {
+	Account string `json:"account"`
+}
+
+type balance struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency,omitempty"`
+}
+
+func TestSchemaDocuments(t *testing.T) {
+	t.Parallel()
+
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.Schema = &ws.SchemaConfig{Path: "/schema", Title: "Game API", Version: "2.1.0"}
+	server := ws.New(cfg)
+	ctx := context.Background()
+
+	server.RegisterHandler(ctx, cmdChatSend, func(ctx context.Context, client knet.Client, payload []byte) {},
+		knet.WithDoc("chat.send", "Sends a message to a room"), knet.WithTypes[chatMessage, struct{}]())
+	server.RegisterRequestHandler(ctx, cmdBalanceQuery, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		return nil, nil
+	}, knet.WithTypes[balanceQuery, balance]())
+	knet.RegisterJSONRPC(ctx, server, "account.balance", func(ctx context.Context, client knet.Client, q balanceQuery) (balance, error) {
+		return balance{}, nil
+	}, knet.WithDoc("Balance", "Returns the balance of an account"))
+	knet.RegisterJSONRPC(ctx, server, "math.subtract", func(ctx context.Context, client knet.Client, args [2]float64) (float64, error) {
+		return args[0] - args[1], nil
+	})
+	server.RegisterJSONRPCHandler(ctx, "status", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	base := "http" + strings.TrimPrefix(serveOnRandomPort(t, server), "ws") + "/schema/"
+
+	get := func(t *testing.T, name string) []byte {
+		t.Helper()
+		resp, err := http.Get(base + name)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", name, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("GET %s = %d %q, want 200 application/json", name, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return body
+	}
+
+	t.Run("openrpc", func(t *testing.T) {
+		var doc knet.OpenRPCDocument
+		if err := json.Unmarshal(get(t, "openrpc.json"), &doc); err != nil {
+			t.Fatalf("Failed to decode document: %v", err)
+		}
+		if doc.OpenRPC != knet.OpenRPCVersion || doc.Info.Title != "Game API" || doc.Info.Version != "2.1.0" {
+			t.Errorf("header = %q %+v, want the configured info", doc.OpenRPC, doc.Info)
+		}
+
+		var names []string
+		for _, m := range doc.Methods {
+			names = append(names, m.Name)
+		}
+		if strings.Join(names, ",") != "account.balance,math.subtract,status" {
+			t.Fatalf("methods = %v, want sorted registered methods", names)
+		}
+
+		accountBalance := doc.Methods[0]
+		if accountBalance.Summary != "Balance" || accountBalance.Description != "Returns the balance of an account" {
+			t.Errorf("account.balance doc = %q %q, want WithDoc values", accountBalance.Summary, accountBalance.Description)
+		}
+		if accountBalance.ParamStructure != "by-name" || len(accountBalance.Params) != 1 ||
+			accountBalance.Params[0].Name != "account" || !accountBalance.Params[0].Required {
+			t.Errorf("account.balance params = %s %+v, want the required account field", accountBalance.ParamStructure, accountBalance.Params)
+		}
+		if ref := accountBalance.Result.Schema["$ref"]; ref != "#/components/schemas/balance" {
+			t.Errorf("account.balance result = %v, want a reference to balance", accountBalance.Result.Schema)
+		}
+		if doc.Components == nil || doc.Components.Schemas["balance"] == nil {
+			t.Errorf("components = %+v, want the balance schema", doc.Components)
+		}
+
+		subtract := doc.Methods[1]
+		if subtract.ParamStructure != "by-position" || len(subtract.Params) != 2 || subtract.Result.Schema["type"] != "number" {
+			t.Errorf("math.subtract = %+v, want two positional params and a number result", subtract)
+		}
+
+		if status := doc.Methods[2]; len(status.Params) != 0 || len(status.Result.Schema) != 0 {
+			t.Errorf("status = %+v, want an untyped method", status)
+		}
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		var catalog knet.CommandCatalog
+		if err := json.Unmarshal(get(t, "commands.json"), &catalog); err != nil {
+			t.Fatalf("Failed to decode catalog: %v", err)
+		}
+		if len(catalog.Commands) != 2 {
+			t.Fatalf("commands = %+v, want 2", catalog.Commands)
+		}
+
+		chat, query := catalog.Commands[0], catalog.Commands[1]
+		if chat.ID != cmdChatSend || chat.Kind != "command" || chat.Name != "chat.send" || chat.Response != nil ||
+			chat.Request["$ref"] != "#/schemas/chatMessage" {
+			t.Errorf("chat.send = %+v, want a documented command without response", chat)
+		}
+		if query.ID != cmdBalanceQuery || query.Kind != "request" || query.Response["$ref"] != "#/schemas/balance" {
+			t.Errorf("balance query = %+v, want a request answered with balance", query)
+		}
+		if catalog.Schemas["chatMessage"] == nil || catalog.Schemas["balanceQuery"] == nil {
+			t.Errorf("schemas = %v, want chatMessage and balanceQuery", catalog.Schemas)
+		}
+	})
+
+	t.Run("unknown document", func(t *testing.T) {
+		resp, err := http.Get(base + "other.json")
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package e2e_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+)
+
+const cmdWhoAmI uint32 = 0x0020
+
+// testPKI holds a throwaway CA with a server and a client certificate
+type testPKI struct {
+	caPool     *x509.CertPool
+	caFile     string
+	certFile   string
+	keyFile    string
+	clientCert tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "knet test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, cn string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("failed to issue %s: %v", cn, err)
+		}
+		return der, key
+	}
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	serverDER, serverKey := issue(2, "server", x509.ExtKeyUsageServerAuth)
+	serverKeyDER, _ := x509.MarshalECPrivateKey(serverKey)
+
+	clientDER, clientKey := issue(3, "device-42", x509.ExtKeyUsageClientAuth)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	return &testPKI{
+		caPool:   pool,
+		caFile:   writePEM("ca.pem", "CERTIFICATE", caDER),
+		certFile: writePEM("server.pem", "CERTIFICATE", serverDER),
+		keyFile:  writePEM("server-key.pem", "EC PRIVATE KEY", serverKeyDER),
+		clientCert: tls.Certificate{
+			Certificate: [][]byte{clientDER},
+			PrivateKey:  clientKey,
+		},
+	}
+}
+
+func TestMutualTLSPeerCertificate(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t)
+
+	tlsConfig, err := ws.NewMutualTLSConfig(pki.certFile, pki.keyFile, pki.caFile)
+	if err != nil {
+		t.Fatalf("NewMutualTLSConfig() error = %v", err)
+	}
+
+	cfg := ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil)
+	cfg.TLSConfig = tlsConfig
+	server := ws.New(cfg)
+
+	server.RegisterHandler(context.Background(), cmdWhoAmI, func(ctx context.Context, client knet.Client, payload []byte) {
+		cert := client.PeerCertificate()
+		if cert == nil {
+			client.Send(context.Background(), cmdWhoAmI, []byte("anonymous"))
+			return
+		}
+		client.Send(context.Background(), cmdWhoAmI, []byte(cert.Subject.CommonName))
+	})
+
+	baseURL := strings.Replace(serveOnRandomPort(t, server), "ws://", "wss://", 1)
+
+	dialer := newDialer()
+	dialer.TLSClientConfig = &tls.Config{
+		RootCAs:      pki.caPool,
+		Certificates: []tls.Certificate{pki.clientCert},
+	}
+
+	conn, _, err := dialer.Dial(baseURL+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect over mTLS: %v", err)
+	}
+	defer conn.Close()
+
+	writeCommand(t, conn, cmdWhoAmI, nil)
+	_, payload := readCommand(t, conn)
+
+	if string(payload) != "device-42" {
+		t.Errorf("peer certificate CN = %q, want %q", payload, "device-42")
+	}
+
+	// Clients without a certificate must be rejected during the TLS handshake
+	anonymous := &websocket.Dialer{
+		HandshakeTimeout: 5 * time.Second,
+		TLSClientConfig:  &tls.Config{RootCAs: pki.caPool},
+	}
+	if conn, _, err := anonymous.Dial(baseURL+"/ws", nil); err == nil {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Error("expected connection without client certificate to be rejected")
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,274 @@
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/ws"
+	"github.com/luciancaetano/knet/wsclient"
+)
+
+const (
+	cmdClientEcho  uint32 = 0x0070
+	cmdClientUpper uint32 = 0x0071
+	cmdClientAsk   uint32 = 0x0072
+	cmdClientNews  uint32 = 0x0073
+)
+
+func newClientTestServer(t *testing.T) (knet.WebsocketServer, string) {
+	t.Helper()
+
+	server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+	ctx := context.Background()
+
+	server.RegisterHandler(ctx, cmdClientEcho, func(ctx context.Context, client knet.Client, payload []byte) {
+		client.Send(ctx, cmdClientEcho, payload)
+	})
+	server.RegisterRequestHandler(ctx, cmdClientUpper, func(ctx context.Context, client knet.Client, payload []byte) ([]byte, error) {
+		return bytes.ToUpper(payload), nil
+	})
+	server.RegisterJSONRPCHandler(ctx, "add", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return params["a"].(float64) + params["b"].(float64), nil
+	})
+	// Asks the client a question and reports the answer back
+	server.RegisterHandler(ctx, cmdClientAsk, func(ctx context.Context, client knet.Client, payload []byte) {
+		answer, err := client.Call(ctx, cmdClientAsk, payload)
+		if err != nil {
+			answer = []byte(err.Error())
+		}
+		client.Send(ctx, cmdClientEcho, answer)
+	})
+
+	return server, serveOnRandomPort(t, server) + "/ws"
+}
+
+func TestGoClient(t *testing.T) {
+	t.Parallel()
+
+	server, url := newClientTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := wsclient.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	if client.State() != wsclient.StateConnected {
+		t.Errorf("State() = %v, want connected", client.State())
+	}
+
+	echoes := make(chan []byte, 4)
+	client.On(cmdClientEcho, func(payload []byte) { echoes <- payload })
+
+	t.Run("send and receive", func(t *testing.T) {
+		if err := client.Send(ctx, cmdClientEcho, []byte("hi")); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		if got := <-echoes; string(got) != "hi" {
+			t.Errorf("echo = %q, want %q", got, "hi")
+		}
+	})
+
+	t.Run("correlated request", func(t *testing.T) {
+		reply, err := client.Request(ctx, cmdClientUpper, []byte("shout"))
+		if err != nil || string(reply) != "SHOUT" {
+			t.Errorf("Request() = (%q, %v), want (%q, nil)", reply, err, "SHOUT")
+		}
+
+		var reqErr *knet.RequestError
+		if _, err := client.Request(ctx, 0x0999, nil); !errors.As(err, &reqErr) || reqErr.Code != knet.RequestErrUnknownCommand {
+			t.Errorf("Request() unknown command error = %v, want RequestErrUnknownCommand", err)
+		}
+	})
+
+	t.Run("json-rpc", func(t *testing.T) {
+		var sum float64
+		if err := client.CallJSONRPC(ctx, "add", map[string]int{"a": 2, "b": 3}, &sum); err != nil || sum != 5 {
+			t.Errorf("CallJSONRPC() = (%v, %v), want (5, nil)", sum, err)
+		}
+
+		var rpcErr *wsclient.JSONRPCError
+		if err := client.CallJSONRPC(ctx, "missing", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != knet.JSONRPCMethodNotFound {
+			t.Errorf("CallJSONRPC() error = %v, want method not found", err)
+		}
+	})
+
+	t.Run("answers server calls", func(t *testing.T) {
+		client.OnRequest(cmdClientAsk, func(payload []byte) ([]byte, error) {
+			return append([]byte("yes: "), payload...), nil
+		})
+
+		client.Send(ctx, cmdClientAsk, []byte("continue?"))
+		if got := <-echoes; string(got) != "yes: continue?" {
+			t.Errorf("server received %q, want %q", got, "yes: continue?")
+		}
+	})
+
+	t.Run("subscribe", func(t *testing.T) {
+		news := make(chan []byte, 1)
+		client.On(cmdClientNews, func(payload []byte) { news <- payload })
+
+		if err := client.Subscribe(ctx, "news.>"); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+		server.Publish(ctx, "news.sports", cmdClientNews, []byte("goal"))
+
+		select {
+		case got := <-news:
+			if string(got) != "goal" {
+				t.Errorf("publication = %q, want %q", got, "goal")
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("publication not received")
+		}
+	})
+
+	t.Run("handlers run in order", func(t *testing.T) {
+		const count = 200
+		received := make(chan int, count)
+		client.On(cmdClientNews, func(payload []byte) {
+			n, _ := strconv.Atoi(string(payload))
+			received <- n
+		})
+
+		for i := 0; i < count; i++ {
+			server.Publish(ctx, "news.sports", cmdClientNews, []byte(strconv.Itoa(i)))
+		}
+		for i := 0; i < count; i++ {
+			select {
+			case n := <-received:
+				if n != i {
+					t.Fatalf("message %d handled in position %d", n, i)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatalf("received %d of %d messages", i, count)
+			}
+		}
+	})
+}
+
+func TestGoClientReconnects(t *testing.T) {
+	t.Parallel()
+
+	newServer := func() knet.WebsocketServer {
+		server := ws.New(ws.NewConfig("", ws.NoRateLimit(), ws.AllOrigins(), nil, nil))
+		server.RegisterHandler(context.Background(), cmdClientEcho, func(ctx context.Context, client knet.Client, payload []byte) {
+			client.Send(context.Background(), cmdClientEcho, payload)
+		})
+		return server
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	first := newServer()
+	go first.Serve(ln)
+
+	states := make(chan wsclient.State, 256)
+	opts := wsclient.DefaultOptions()
+	opts.MinBackoff = 20 * time.Millisecond
+	opts.MaxBackoff = 100 * time.Millisecond
+	opts.OnStateChange = func(state wsclient.State, err error) { states <- state }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := wsclient.Dial(ctx, "ws://"+addr+"/ws", opts)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	waitFor := func(want wsclient.State) {
+		t.Helper()
+		for {
+			select {
+			case state := <-states:
+				if state == want {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("state %v not reached", want)
+			}
+		}
+	}
+
+	waitFor(wsclient.StateConnected)
+	first.Stop(context.Background())
+	waitFor(wsclient.StateDisconnected)
+	waitFor(wsclient.StateReconnecting)
+
+	// Bring a new server up on the same address
+	var ln2 net.Listener
+	for ln2 == nil {
+		if ln2, err = net.Listen("tcp", addr); err != nil {
+			ln2 = nil
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	second := newServer()
+	go second.Serve(ln2)
+	defer second.Stop(context.Background())
+
+	waitFor(wsclient.StateConnected)
+
+	echoes := make(chan []byte, 1)
+	client.On(cmdClientEcho, func(payload []byte) { echoes <- payload })
+	if err := client.Send(ctx, cmdClientEcho, []byte("back")); err != nil {
+		t.Fatalf("Send() after reconnect error = %v", err)
+	}
+	select {
+	case got := <-echoes:
+		if string(got) != "back" {
+			t.Errorf("echo = %q, want %q", got, "back")
+		}
+	case <-ctx.Done():
+		t.Fatal("no echo after reconnect")
+	}
+
+	client.Close()
+	waitFor(wsclient.StateClosed)
+}
+
+func TestGoClientGoingAway(t *testing.T) {
+	t.Parallel()
+
+	server, url := newClientTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hints := make(chan string, 1)
+	opts := wsclient.DefaultOptions()
+	opts.Reconnect = false
+	opts.OnGoingAway = func(hint string) { hints <- hint }
+
+	client, err := wsclient.Dial(ctx, url, opts)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	if err := server.Drain(ctx, "ws://elsewhere/ws"); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+
+	select {
+	case hint := <-hints:
+		if hint != "ws://elsewhere/ws" {
+			t.Errorf("hint = %q, want %q", hint, "ws://elsewhere/ws")
+		}
+	case <-ctx.Done():
+		t.Fatal("OnGoingAway was not called")
+	}
+}
@@ -54,7 +54,7 @@ func startTestServer(t *testing.T, ctx context.Context) knet.WebsocketServer {
 	}, nil))
 
 	// Register chat message handler
-	err := server.RegisterHandler(ctx, ChatMessageCommand, func(client knet.Client, payload []byte) {
+	err := server.RegisterHandler(ctx, ChatMessageCommand, func(ctx context.Context, client knet.Client, payload []byte) {
 		var msg ChatMessage
 		if err := json.Unmarshal(payload, &msg); err != nil {
 			return
@@ -24,6 +24,30 @@ func TestConstants(t *testing.T) {
 		if knet.CmdJSONRPCError != 0xFFFFFFFE {
 			t.Errorf("CmdJSONRPCError = %v, want 0xFFFFFFFE", knet.CmdJSONRPCError)
 		}
+
+		// Verify every reserved command is distinct and inside the reserved range
+		reserved := map[string]uint32{
+			"CmdJSONRPC":          knet.CmdJSONRPC,
+			"CmdJSONRPCError":     knet.CmdJSONRPCError,
+			"CmdSubscribe":        knet.CmdSubscribe,
+			"CmdUnsubscribe":      knet.CmdUnsubscribe,
+			"CmdSubscribeDenied":  knet.CmdSubscribeDenied,
+			"CmdRequest":          knet.CmdRequest,
+			"CmdResponse":         knet.CmdResponse,
+			"CmdResponseError":    knet.CmdResponseError,
+			"CmdGoingAway":        knet.CmdGoingAway,
+			"CmdPermissionDenied": knet.CmdPermissionDenied,
+		}
+		seen := make(map[uint32]string)
+		for name, id := range reserved {
+			if id < knet.CmdReservedMin {
+				t.Errorf("%s = 0x%X is below CmdReservedMin", name, id)
+			}
+			if other, ok := seen[id]; ok {
+				t.Errorf("%s and %s share command ID 0x%X", name, other, id)
+			}
+			seen[id] = name
+		}
 	})
 
 	t.Run("error messages", func(t *testing.T) {
@@ -43,6 +67,17 @@ func TestConstants(t *testing.T) {
 			{"ErrContextCancelled", knet.ErrContextCancelled},
 			{"ErrFailedToEncode", knet.ErrFailedToEncode},
 			{"ErrServerAlreadyRunning", knet.ErrServerAlreadyRunning},
+			{"ErrReservedCommand", knet.ErrReservedCommand},
+			{"ErrInvalidRoom", knet.ErrInvalidRoom},
+			{"ErrInvalidTopic", knet.ErrInvalidTopic},
+			{"ErrInvalidParams", knet.ErrInvalidParams},
+			{"ErrDispatchQueueFull", knet.ErrDispatchQueueFull},
+			{"ErrServerDraining", knet.ErrServerDraining},
+			{"ErrSendQueueFull", knet.ErrSendQueueFull},
+			{"ErrAuthenticationRequired", knet.ErrAuthenticationRequired},
+			{"ErrAuthenticationFailed", knet.ErrAuthenticationFailed},
+			{"ErrAuthenticationTimeout", knet.ErrAuthenticationTimeout},
+			{"ErrPermissionDenied", knet.ErrPermissionDenied},
 		}
 
 		for _, em := range errorMessages {
@@ -1,7 +1,11 @@
 package ws
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/luciancaetano/knet"
 	"github.com/luciancaetano/knet/internal/websocket"
@@ -11,8 +15,57 @@ type RateLimitConfig = websocket.RateLimitConfig
 type CheckOriginFn = websocket.CheckOriginFn
 type OnConnectFn = websocket.OnConnectFn
 type OnDisconnectFn = websocket.OnClientDisconnectFn
+type AuthenticateFn = websocket.AuthenticateFn
+type InBandAuthConfig = websocket.InBandAuthConfig
+type ValidateCredentialsFn = websocket.ValidateCredentialsFn
+type AuthorizeSubscribeFn = websocket.AuthorizeSubscribeFn
+type OnHandlerErrorFn = websocket.OnHandlerErrorFn
+type OnPermissionDeniedFn = websocket.OnPermissionDeniedFn
+type PanicPolicy = websocket.PanicPolicy
+type DispatchConfig = websocket.DispatchConfig
+type DispatchMode = websocket.DispatchMode
+type QueueFullPolicy = websocket.QueueFullPolicy
+type SendQueueConfig = websocket.SendQueueConfig
+type SendQueuePolicy = websocket.SendQueuePolicy
+type OnSlowConsumerFn = websocket.OnSlowConsumerFn
+type JSONRPCError = websocket.JSONRPCError
+type JSONRPCErrorMapperFn = websocket.JSONRPCErrorMapperFn
+type SchemaConfig = websocket.SchemaConfig
 type ServerConfig = *websocket.ServerConfig
 
+// Panic policies for ServerConfig.PanicPolicy
+const (
+	PanicKeepConnection  = websocket.PanicKeepConnection
+	PanicCloseConnection = websocket.PanicCloseConnection
+	PanicRepanic         = websocket.PanicRepanic
+)
+
+// Dispatch modes for DispatchConfig.Mode
+const (
+	DispatchUnbounded  = websocket.DispatchUnbounded
+	DispatchWorkerPool = websocket.DispatchWorkerPool
+	DispatchPerClient  = websocket.DispatchPerClient
+)
+
+// Queue full policies for DispatchConfig.QueueFullPolicy
+const (
+	QueueFullBlock      = websocket.QueueFullBlock
+	QueueFullDrop       = websocket.QueueFullDrop
+	QueueFullDisconnect = websocket.QueueFullDisconnect
+)
+
+// Send queue policies for SendQueueConfig.Policy
+const (
+	SendQueueBlock      = websocket.SendQueueBlock
+	SendQueueDropNewest = websocket.SendQueueDropNewest
+	SendQueueDropOldest = websocket.SendQueueDropOldest
+	SendQueueCoalesce   = websocket.SendQueueCoalesce
+	SendQueueDisconnect = websocket.SendQueueDisconnect
+)
+
+// DefaultPath is the HTTP path used by Start and Serve when ServerConfig.Path is empty
+const DefaultPath = websocket.DefaultPath
+
 // New creates a new WebSocket server with rate limiting and connection callbacks.
 //
 // Parameters:
@@ -59,3 +112,41 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 func NoRateLimit() *RateLimitConfig {
 	return websocket.NoRateLimit()
 }
+
+// NewTLSConfig loads a server certificate and key from PEM files and returns a
+// TLS configuration suitable for ServerConfig.TLSConfig.
+func NewTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// NewMutualTLSConfig is like NewTLSConfig but also requires clients to present a
+// certificate signed by one of the CAs in clientCAFile (PEM). The verified
+// certificate is available through knet.Client.PeerCertificate.
+func NewMutualTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cfg, err := NewTLSConfig(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return cfg, nil
+}
@@ -0,0 +1,23 @@
+package wsclient
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// backoff returns the delay before reconnect attempt n (starting at 1):
+// MinBackoff doubled per attempt, capped at MaxBackoff, reduced by up to Jitter
+func (o *Options) backoff(attempt int) time.Duration {
+	delay := o.MinBackoff
+	for i := 1; i < attempt && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if o.MaxBackoff > 0 && delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+
+	if o.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * o.Jitter * float64(delay))
+	}
+	return delay
+}
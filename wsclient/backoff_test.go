@@ -0,0 +1,80 @@
+package wsclient
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoffGrowsExponentially tests that delays double and are capped
+func TestBackoffGrowsExponentially(t *testing.T) {
+	t.Parallel()
+
+	opts := &Options{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := opts.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+}
+
+// TestBackoffJitter tests that jitter only shortens the delay, within bounds
+func TestBackoffJitter(t *testing.T) {
+	t.Parallel()
+
+	opts := &Options{MinBackoff: time.Second, MaxBackoff: time.Second, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		got := opts.backoff(1)
+		if got > time.Second || got < 500*time.Millisecond {
+			t.Fatalf("backoff(1) = %v, want within [500ms, 1s]", got)
+		}
+	}
+}
+
+// TestOptionsWithDefaults tests that partial options get default durations
+// without modifying the caller's Options
+func TestOptionsWithDefaults(t *testing.T) {
+	t.Parallel()
+
+	partial := &Options{Reconnect: true, MaxBackoff: 5 * time.Second}
+	opts := partial.withDefaults()
+	defaults := DefaultOptions()
+
+	if opts.MinBackoff != defaults.MinBackoff || opts.HandshakeTimeout != defaults.HandshakeTimeout {
+		t.Errorf("withDefaults() = %+v, want default MinBackoff and HandshakeTimeout", opts)
+	}
+	if opts.MaxBackoff != 5*time.Second || !opts.Reconnect {
+		t.Errorf("withDefaults() = %+v, want the fields that were set kept", opts)
+	}
+	if partial.MinBackoff != 0 || partial.HandshakeTimeout != 0 {
+		t.Errorf("caller options modified: %+v", partial)
+	}
+
+	if got := (&Options{Reconnect: true}).withDefaults().backoff(1); got != defaults.MinBackoff {
+		t.Errorf("backoff(1) = %v, want %v", got, defaults.MinBackoff)
+	}
+	if got := (*Options)(nil).withDefaults(); got.MaxBackoff != defaults.MaxBackoff || !got.Reconnect {
+		t.Errorf("nil.withDefaults() = %+v, want DefaultOptions()", got)
+	}
+}
+
+// TestStateString tests state names
+func TestStateString(t *testing.T) {
+	t.Parallel()
+
+	states := map[State]string{
+		StateConnecting:   "connecting",
+		StateConnected:    "connected",
+		StateDisconnected: "disconnected",
+		StateReconnecting: "reconnecting",
+		StateClosed:       "closed",
+		State(99):         "unknown",
+	}
+	for state, want := range states {
+		if got := state.String(); got != want {
+			t.Errorf("State(%d).String() = %q, want %q", state, got, want)
+		}
+	}
+}
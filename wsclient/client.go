@@ -0,0 +1,620 @@
+// Package wsclient is the Go client for knet servers.
+//
+// It speaks the same binary command protocol as the server, supports correlated
+// requests in both directions, JSON-RPC calls and topic subscriptions, and
+// reconnects automatically with exponential backoff and jitter.
+//
+// Example:
+//
+//	client, err := wsclient.Dial(ctx, "ws://localhost:8080/ws", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer client.Close()
+//
+//	client.On(0x0001, func(payload []byte) {
+//	    log.Printf("chat: %s", payload)
+//	})
+//	client.Send(ctx, 0x0001, []byte("hello"))
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/luciancaetano/knet"
+	"github.com/luciancaetano/knet/internal/protocol"
+)
+
+// Errors returned by Client methods
+var (
+	// ErrNotConnected is returned when sending while the connection is down
+	ErrNotConnected = errors.New("wsclient: not connected")
+	// ErrClosed is returned after Close has been called
+	ErrClosed = errors.New("wsclient: client closed")
+	// ErrDisconnected fails requests that were pending when the connection dropped
+	ErrDisconnected = errors.New("wsclient: disconnected while waiting for reply")
+	// ErrSubscriptionDenied is returned when the server rejects a subscription
+	ErrSubscriptionDenied = errors.New("wsclient: subscription denied")
+)
+
+// writeTimeout bounds writes whose context has no deadline
+const writeTimeout = 10 * time.Second
+
+// handlerQueueSize is the number of messages of one connection waiting for
+// their On handler; reading pauses while the queue is full
+const handlerQueueSize = 256
+
+// JSONRPCError is the error object of a failed JSON-RPC call
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// reply is delivered to a pending request
+type reply struct {
+	payload []byte
+	err     error
+}
+
+// Client is a connection to a knet server. All methods are safe for concurrent use.
+type Client struct {
+	url    string
+	opts   *Options
+	dialer *websocket.Dialer
+
+	handlers        sync.Map // map[uint32]func(payload []byte)
+	requestHandlers sync.Map // map[uint32]func(payload []byte) ([]byte, error)
+	jsonRPCHandlers sync.Map // map[string]func(params json.RawMessage) (any, error)
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	state         State
+	closed        bool
+	subscriptions map[string]struct{}
+
+	// gorilla connections support a single concurrent writer
+	writeMu sync.Mutex
+
+	// Pending replies keyed by kind and ID ("req:7", "rpc:7", "sub:orders.*")
+	pendingMu sync.Mutex
+	pending   map[string]chan reply
+	nextID    atomic.Uint32
+
+	done chan struct{}
+}
+
+// Dial connects to a knet server and returns once the connection is ready.
+//
+// The first connection attempt is not retried; its error is returned directly.
+// When Options.Reconnect is set, later disconnects are followed by reconnect
+// attempts in the background, reported through Options.OnStateChange.
+// A nil opts uses DefaultOptions(); opts is not modified.
+func Dial(ctx context.Context, url string, opts *Options) (*Client, error) {
+	opts = opts.withDefaults()
+
+	c := &Client{
+		url:  url,
+		opts: opts,
+		dialer: &websocket.Dialer{
+			HandshakeTimeout: opts.HandshakeTimeout,
+			TLSClientConfig:  opts.TLSConfig,
+		},
+		subscriptions: make(map[string]struct{}),
+		pending:       make(map[string]chan reply),
+		done:          make(chan struct{}),
+	}
+
+	c.setState(StateConnecting, nil)
+	conn, _, err := c.dialer.DialContext(ctx, url, opts.Header)
+	if err != nil {
+		c.setState(StateClosed, err)
+		return nil, err
+	}
+
+	c.attach(conn)
+	go c.run(conn)
+	return c, nil
+}
+
+// On registers a handler for messages the server sends with commandID.
+// Handlers of one connection run one at a time, in arrival order, outside the
+// read loop, so they may call Request or CallJSONRPC. A slow handler delays
+// the messages that follow it.
+func (c *Client) On(commandID uint32, handler func(payload []byte)) {
+	c.handlers.Store(commandID, handler)
+}
+
+// Off removes the handler for commandID
+func (c *Client) Off(commandID uint32) {
+	c.handlers.Delete(commandID)
+}
+
+// OnRequest registers a handler answering calls the server makes with knet.Client.Call.
+// Return a *knet.RequestError to choose the error code sent back.
+func (c *Client) OnRequest(commandID uint32, handler func(payload []byte) ([]byte, error)) {
+	c.requestHandlers.Store(commandID, handler)
+}
+
+// OnJSONRPC registers a handler for a JSON-RPC method the server calls with
+// knet.Client.CallJSONRPC or NotifyJSONRPC. The returned value is sent as the
+// result of calls and ignored for notifications. Return a *JSONRPCError or a
+// *knet.RPCError to choose the error code sent back.
+func (c *Client) OnJSONRPC(method string, handler func(params json.RawMessage) (any, error)) {
+	c.jsonRPCHandlers.Store(method, handler)
+}
+
+// State returns the current connection state
+func (c *Client) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Send sends a fire-and-forget command to the server.
+// Returns ErrNotConnected while the connection is down.
+func (c *Client) Send(ctx context.Context, commandID uint32, payload []byte) error {
+	data, err := protocol.Encode(commandID, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", knet.ErrFailedToEncode, err)
+	}
+	return c.write(ctx, data)
+}
+
+// Request sends a correlated request to a handler registered with
+// RegisterRequestHandler and waits for the reply. A *knet.RequestError is
+// returned when the server answers with an error frame.
+func (c *Client) Request(ctx context.Context, commandID uint32, payload []byte) ([]byte, error) {
+	requestID := c.nextID.Add(1)
+	return c.roundTrip(ctx, "req:"+strconv.FormatUint(uint64(requestID), 10), knet.CmdRequest,
+		protocol.EncodeRequest(commandID, requestID, payload))
+}
+
+// CallJSONRPC calls a JSON-RPC method and decodes its result into result (which may be nil).
+// A *JSONRPCError is returned when the server answers with an error object.
+func (c *Client) CallJSONRPC(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	request, err := json.Marshal(struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+		ID      uint32 `json:"id"`
+	}{knet.JSONRPCVersion, method, params, id})
+	if err != nil {
+		return err
+	}
+
+	raw, err := c.roundTrip(ctx, "rpc:"+strconv.FormatUint(uint64(id), 10), knet.CmdJSONRPC, request)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// Subscribe subscribes to a topic pattern and waits for the server to approve it.
+// Subscriptions are restored automatically after a reconnect.
+func (c *Client) Subscribe(ctx context.Context, pattern string) error {
+	if _, err := c.roundTrip(ctx, "sub:"+pattern, knet.CmdSubscribe, []byte(pattern)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.subscriptions[pattern] = struct{}{}
+	c.mu.Unlock()
+	return nil
+}
+
+// Unsubscribe drops a topic subscription
+func (c *Client) Unsubscribe(ctx context.Context, pattern string) error {
+	c.mu.Lock()
+	delete(c.subscriptions, pattern)
+	c.mu.Unlock()
+
+	_, err := c.roundTrip(ctx, "unsub:"+pattern, knet.CmdUnsubscribe, []byte(pattern))
+	return err
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	close(c.done)
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	return conn.Close()
+}
+
+// roundTrip sends body on commandID and waits for the reply registered under key
+func (c *Client) roundTrip(ctx context.Context, key string, commandID uint32, body []byte) ([]byte, error) {
+	replyCh := make(chan reply, 1)
+
+	c.pendingMu.Lock()
+	c.pending[key] = replyCh
+	c.pendingMu.Unlock()
+
+	defer func() {
+		c.pendingMu.Lock()
+		if c.pending[key] == replyCh {
+			delete(c.pending, key)
+		}
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.Send(ctx, commandID, body); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-replyCh:
+		return r.payload, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, ErrClosed
+	}
+}
+
+// resolve delivers a reply to the request pending under key, if any
+func (c *Client) resolve(key string, r reply) {
+	c.pendingMu.Lock()
+	replyCh, ok := c.pending[key]
+	if ok {
+		delete(c.pending, key)
+	}
+	c.pendingMu.Unlock()
+
+	if ok {
+		replyCh <- r
+	}
+}
+
+// failPending fails every pending request with err
+func (c *Client) failPending(err error) {
+	c.pendingMu.Lock()
+	pending := c.pending
+	c.pending = make(map[string]chan reply)
+	c.pendingMu.Unlock()
+
+	for _, replyCh := range pending {
+		replyCh <- reply{err: err}
+	}
+}
+
+// write sends an encoded frame on the current connection
+func (c *Client) write(ctx context.Context, data []byte) error {
+	c.mu.Lock()
+	conn, closed := c.conn, c.closed
+	c.mu.Unlock()
+
+	if closed {
+		return ErrClosed
+	}
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(writeTimeout)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	conn.SetWriteDeadline(deadline)
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// setState records the state and notifies OnStateChange
+func (c *Client) setState(state State, err error) {
+	c.mu.Lock()
+	c.state = state
+	c.mu.Unlock()
+
+	if c.opts.OnStateChange != nil {
+		c.opts.OnStateChange(state, err)
+	}
+}
+
+// attach makes conn the active connection. It returns false, and closes conn,
+// when Close has already been called.
+func (c *Client) attach(conn *websocket.Conn) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return false
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	c.setState(StateConnected, nil)
+	return true
+}
+
+// run reads from conn and reconnects when it drops, until Close is called
+// or reconnecting gives up
+func (c *Client) run(conn *websocket.Conn) {
+	for {
+		err := c.readLoop(conn)
+
+		c.mu.Lock()
+		c.conn = nil
+		closed := c.closed
+		c.mu.Unlock()
+		conn.Close()
+
+		c.failPending(ErrDisconnected)
+
+		if closed {
+			c.setState(StateClosed, nil)
+			return
+		}
+
+		c.setState(StateDisconnected, err)
+		if !c.opts.Reconnect {
+			c.setState(StateClosed, err)
+			return
+		}
+
+		if conn = c.reconnect(); conn == nil {
+			return
+		}
+		if !c.attach(conn) {
+			// Close was called while dialing
+			c.setState(StateClosed, nil)
+			return
+		}
+		c.restoreSubscriptions()
+	}
+}
+
+// reconnect dials with exponential backoff until it succeeds, Close is called or
+// MaxReconnectAttempts is exhausted. Returns nil when it gives up.
+func (c *Client) reconnect() *websocket.Conn {
+	for attempt := 1; ; attempt++ {
+		if c.opts.MaxReconnectAttempts > 0 && attempt > c.opts.MaxReconnectAttempts {
+			c.setState(StateClosed, fmt.Errorf("wsclient: gave up after %d reconnect attempts", c.opts.MaxReconnectAttempts))
+			return nil
+		}
+
+		timer := time.NewTimer(c.opts.backoff(attempt))
+		select {
+		case <-c.done:
+			timer.Stop()
+			c.setState(StateClosed, nil)
+			return nil
+		case <-timer.C:
+		}
+
+		c.setState(StateReconnecting, nil)
+		conn, _, err := c.dialer.Dial(c.url, c.opts.Header)
+		if err != nil {
+			c.setState(StateDisconnected, err)
+			continue
+		}
+		return conn
+	}
+}
+
+// restoreSubscriptions re-sends every active subscription after a reconnect
+func (c *Client) restoreSubscriptions() {
+	c.mu.Lock()
+	patterns := make([]string, 0, len(c.subscriptions))
+	for pattern := range c.subscriptions {
+		patterns = append(patterns, pattern)
+	}
+	c.mu.Unlock()
+
+	for _, pattern := range patterns {
+		c.Send(context.Background(), knet.CmdSubscribe, []byte(pattern))
+	}
+}
+
+// readLoop reads and dispatches frames until the connection fails
+func (c *Client) readLoop(conn *websocket.Conn) error {
+	queue := make(chan func(), handlerQueueSize)
+	go runQueue(queue)
+	defer close(queue)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		commandID, payload, err := protocol.Decode(data)
+		if err != nil {
+			continue
+		}
+		c.dispatch(commandID, payload, queue)
+	}
+}
+
+// runQueue runs tasks in order until the queue is closed
+func runQueue(queue <-chan func()) {
+	for task := range queue {
+		task()
+	}
+}
+
+// dispatch routes a frame to pending requests or registered handlers.
+// On handlers are queued on the connection's ordered queue; request and
+// JSON-RPC handlers get their own goroutine, since they may wait on replies.
+func (c *Client) dispatch(commandID uint32, payload []byte, queue chan<- func()) {
+	switch commandID {
+	case knet.CmdResponse:
+		if requestID, body, err := protocol.DecodeResponse(payload); err == nil {
+			c.resolve("req:"+strconv.FormatUint(uint64(requestID), 10), reply{payload: body})
+		}
+	case knet.CmdResponseError:
+		if requestID, code, message, err := protocol.DecodeError(payload); err == nil {
+			c.resolve("req:"+strconv.FormatUint(uint64(requestID), 10), reply{err: &knet.RequestError{Code: code, Message: message}})
+		}
+	case knet.CmdRequest:
+		go c.handleServerRequest(payload)
+	case knet.CmdJSONRPC:
+		c.handleJSONRPC(payload)
+	case knet.CmdSubscribe:
+		c.resolve("sub:"+string(payload), reply{})
+	case knet.CmdUnsubscribe:
+		c.resolve("unsub:"+string(payload), reply{})
+	case knet.CmdSubscribeDenied:
+		c.resolve("sub:"+string(payload), reply{err: fmt.Errorf("%w: %s", ErrSubscriptionDenied, payload)})
+	case knet.CmdGoingAway:
+		if c.opts.OnGoingAway != nil {
+			c.opts.OnGoingAway(string(payload))
+		}
+	default:
+		if handler, ok := c.handlers.Load(commandID); ok {
+			select {
+			case queue <- func() { handler.(func([]byte))(payload) }:
+			case <-c.done:
+			}
+		}
+	}
+}
+
+// handleServerRequest answers a call made by the server
+func (c *Client) handleServerRequest(body []byte) {
+	commandID, requestID, payload, err := protocol.DecodeRequest(body)
+	if err != nil {
+		return
+	}
+
+	handler, ok := c.requestHandlers.Load(commandID)
+	if !ok {
+		message := fmt.Sprintf("%s: 0x%08X", knet.ErrUnknownCommand, commandID)
+		c.Send(context.Background(), knet.CmdResponseError, protocol.EncodeError(requestID, knet.RequestErrUnknownCommand, message))
+		return
+	}
+
+	result, err := handler.(func([]byte) ([]byte, error))(payload)
+	if err != nil {
+		reqErr := &knet.RequestError{Code: knet.RequestErrInternal, Message: err.Error()}
+		errors.As(err, &reqErr)
+		c.Send(context.Background(), knet.CmdResponseError, protocol.EncodeError(requestID, reqErr.Code, reqErr.Message))
+		return
+	}
+	c.Send(context.Background(), knet.CmdResponse, protocol.EncodeResponse(requestID, result))
+}
+
+// handleJSONRPC resolves the pending call matching a JSON-RPC response, or
+// hands requests and notifications from the server to handleServerJSONRPC
+func (c *Client) handleJSONRPC(payload []byte) {
+	var response struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+		ID     json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return
+	}
+
+	if response.Method != "" {
+		go c.handleServerJSONRPC(response.Method, response.Params, response.ID)
+		return
+	}
+
+	key := "rpc:" + string(response.ID)
+	if response.Error != nil {
+		c.resolve(key, reply{err: response.Error})
+		return
+	}
+	c.resolve(key, reply{payload: response.Result})
+}
+
+// handleServerJSONRPC runs the handler of a JSON-RPC method called by the
+// server and answers it, unless the request is a notification (id is nil)
+func (c *Client) handleServerJSONRPC(method string, params, id json.RawMessage) {
+	handler, ok := c.jsonRPCHandlers.Load(method)
+	if !ok {
+		if id != nil {
+			c.answerJSONRPC(id, nil, &JSONRPCError{Code: knet.JSONRPCMethodNotFound, Message: knet.ErrMethodNotFound})
+		}
+		return
+	}
+
+	result, err := handler.(func(json.RawMessage) (any, error))(params)
+	if id == nil {
+		return
+	}
+	if err != nil {
+		c.answerJSONRPC(id, nil, toJSONRPCError(err))
+		return
+	}
+	c.answerJSONRPC(id, result, nil)
+}
+
+// answerJSONRPC sends the response to a JSON-RPC call made by the server
+func (c *Client) answerJSONRPC(id json.RawMessage, result any, rpcErr *JSONRPCError) {
+	response := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result,omitempty"`
+		Error   *JSONRPCError   `json:"error,omitempty"`
+		ID      json.RawMessage `json:"id"`
+	}{JSONRPC: knet.JSONRPCVersion, Error: rpcErr, ID: id}
+
+	if rpcErr == nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			response.Error = &JSONRPCError{Code: knet.JSONRPCInternalError, Message: knet.ErrInternalError}
+		} else {
+			response.Result = encoded
+		}
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	c.Send(context.Background(), knet.CmdJSONRPC, body)
+}
+
+// toJSONRPCError converts a handler error into the error object sent back
+func toJSONRPCError(err error) *JSONRPCError {
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	var knetErr *knet.RPCError
+	if errors.As(err, &knetErr) {
+		rpcErr = &JSONRPCError{Code: knetErr.Code, Message: knetErr.Message}
+		if knetErr.Data != nil {
+			rpcErr.Data, _ = json.Marshal(knetErr.Data)
+		}
+		return rpcErr
+	}
+	return &JSONRPCError{Code: knet.JSONRPCInternalError, Message: err.Error()}
+}
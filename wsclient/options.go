@@ -0,0 +1,111 @@
+package wsclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// State describes the connection state of a Client.
+type State int
+
+const (
+	// StateConnecting is reported while the first connection is being established.
+	StateConnecting State = iota
+	// StateConnected is reported once the connection is ready to send and receive.
+	StateConnected
+	// StateDisconnected is reported when an established connection is lost.
+	StateDisconnected
+	// StateReconnecting is reported before each reconnect attempt.
+	StateReconnecting
+	// StateClosed is reported after Close, or when reconnecting gives up.
+	StateClosed
+)
+
+// String returns the state name
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// OnStateChangeFn is called on every connection state transition.
+// err carries the cause of a disconnect or a failed reconnect attempt, and is nil otherwise.
+//
+// Note: This function is called synchronously from the connection goroutine.
+// Avoid long-running operations.
+type OnStateChangeFn = func(state State, err error)
+
+// Options configures a Client. A nil *Options uses DefaultOptions().
+// Zero HandshakeTimeout, MinBackoff and MaxBackoff take their DefaultOptions() values.
+type Options struct {
+	// Header is sent with every handshake request (e.g. Authorization, cookies).
+	Header http.Header
+	// TLSConfig is used for wss:// URLs.
+	TLSConfig *tls.Config
+	// HandshakeTimeout bounds each connection attempt.
+	HandshakeTimeout time.Duration
+
+	// Reconnect enables automatic reconnection after an established connection drops.
+	Reconnect bool
+	// MinBackoff is the delay before the first reconnect attempt.
+	MinBackoff time.Duration
+	// MaxBackoff caps the exponentially growing delay between attempts.
+	MaxBackoff time.Duration
+	// Jitter randomizes each delay by up to this fraction (0 to 1) to avoid
+	// every client reconnecting at the same instant.
+	Jitter float64
+	// MaxReconnectAttempts stops reconnecting after this many consecutive
+	// failures. Zero means retry forever.
+	MaxReconnectAttempts int
+
+	// OnStateChange is notified of connection state transitions. Can be nil.
+	OnStateChange OnStateChangeFn
+	// OnGoingAway is called when the server announces it is draining, with the
+	// hint it sent (e.g. an alternative URL). The connection is closed shortly
+	// after and, with Reconnect enabled, re-established. Can be nil.
+	OnGoingAway func(hint string)
+}
+
+// DefaultOptions returns the default client options:
+// reconnect enabled, backoff from 500ms up to 30s with 20% jitter, unlimited attempts
+func DefaultOptions() *Options {
+	return &Options{
+		HandshakeTimeout: 10 * time.Second,
+		Reconnect:        true,
+		MinBackoff:       500 * time.Millisecond,
+		MaxBackoff:       30 * time.Second,
+		Jitter:           0.2,
+	}
+}
+
+// withDefaults returns a copy of o whose zero durations are set from DefaultOptions()
+func (o *Options) withDefaults() *Options {
+	defaults := DefaultOptions()
+	if o == nil {
+		return defaults
+	}
+
+	opts := *o
+	if opts.HandshakeTimeout <= 0 {
+		opts.HandshakeTimeout = defaults.HandshakeTimeout
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaults.MinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+	return &opts
+}